@@ -0,0 +1,510 @@
+// Package admin provides an http.Handler, which exposes JSON endpoints to
+// inspect and control synchronized cron tasks and their recorded runs:
+//
+//	GET  /tasks                  list all tasks with their next run times and state
+//	GET  /tasks/{name}           show a single task
+//	POST /tasks/{name}/trigger   execute a task now, via ExecuteNow - asynchronously
+//	POST /tasks/{name}/pause     pause a task on all instances
+//	POST /tasks/{name}/resume    resume a paused task
+//	GET  /runs?offset=0&limit=20 list recorded runs of the time keeper, latest first
+//	GET  /runs/last              list the last recorded run of all tasks
+//	GET  /runs/last/{name}       show the last recorded run of a single task
+//
+// Paths are relative to the handler, so it can be mounted under any prefix
+// via http.StripPrefix. Task names are path escaped.
+//
+// Triggered executions outlive the request, so the trigger endpoint only reports
+// that the trigger was accepted. Whether the task was actually executed - or
+// skipped, as it is running already or another instance holds its lock - is only
+// visible in the logs of the task and the runs recorded by the time keeper.
+package admin
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRunsLimit is the number of runs listed, if no limit is given.
+	DefaultRunsLimit = 20
+
+	// MaxRunsLimit is the maximum number of runs listed at once.
+	MaxRunsLimit = 1000
+)
+
+// Task is a task exposed by the handler. It is implemented
+// by *crontask.SynchronizedCronTask.
+type Task interface {
+	Name() string
+	NextTime() time.Time
+	ExecuteNow()
+
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+	State(ctx context.Context) (crontask.TaskState, error)
+}
+
+// RunHistory provides the recorded runs of tasks. It is
+// implemented by *timekeeper.TimeKeeper.
+type RunHistory interface {
+	GetAllRuns(ctx context.Context, offset, limit int64) ([]timekeeper.ExecutionResult, error)
+	CountAllRuns(ctx context.Context) (int64, error)
+	GetLastRunOfAllTasks(ctx context.Context) ([]timekeeper.ExecutionResult, error)
+	GetLastRunOfTask(ctx context.Context, name string) (timekeeper.ExecutionResult, error)
+}
+
+var (
+	_ Task       = (*crontask.SynchronizedCronTask)(nil)
+	_ RunHistory = (*timekeeper.TimeKeeper)(nil)
+)
+
+// Action describes what a request intends to do.
+type Action string
+
+const (
+	// ActionRead is the action of all GET requests.
+	ActionRead Action = "read"
+
+	// ActionTrigger is the action of triggering a task.
+	ActionTrigger Action = "trigger"
+
+	// ActionPause is the action of pausing a task.
+	ActionPause Action = "pause"
+
+	// ActionResume is the action of resuming a task.
+	ActionResume Action = "resume"
+)
+
+// ErrUnauthenticated signals that a request carries no valid credentials.
+// If returned by an Authorizer, the request is answered with 401 Unauthorized.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authorizer decides, if a request may perform the given action. A nil error
+// authorizes the request. ErrUnauthenticated results in 401 Unauthorized, and
+// every other error in 403 Forbidden - with the error as the reason.
+type Authorizer func(r *http.Request, action Action) error
+
+// ErrNoAuthorizer signals that a handler was created without an authorizer.
+// Use AllowAll, if the handler is protected otherwise.
+var ErrNoAuthorizer = errors.New("an authorizer is required - use admin.AllowAll() to authorize all requests")
+
+// AllowAll returns an authorizer, which authorizes all requests. The handler
+// must be protected otherwise then, e.g. by only exposing it internally.
+func AllowAll() Authorizer {
+	return func(r *http.Request, action Action) error {
+		return nil
+	}
+}
+
+// BearerToken returns an authorizer, which authorizes all requests
+// carrying the given token via the "Authorization: Bearer" header.
+func BearerToken(token string) Authorizer {
+	return func(r *http.Request, action Action) error {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			return ErrUnauthenticated
+		}
+
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(token)) != 1 {
+			return ErrUnauthenticated
+		}
+
+		return nil
+	}
+}
+
+// Handler is an http.Handler exposing JSON endpoints for tasks and their
+// recorded runs. See the package documentation for all endpoints.
+type Handler struct {
+	tasks map[string]Task
+	names []string
+
+	timeKeeper RunHistory
+	authorizer Authorizer
+
+	logger *logrus.Logger
+}
+
+// NewHandlerWithOptions creates a new Handler instance, or errors out if
+// several tasks share the same name, or no authorizer is set.
+func NewHandlerWithOptions(options *Options) (*Handler, error) {
+	if options.Authorizer == nil {
+		return nil, ErrNoAuthorizer
+	}
+
+	if options.Logger == nil {
+		// Create a "noop" logger, so we don't have to check for
+		// the logger being nil
+		logger := logrus.New()
+		logger.Out = io.Discard
+
+		options.Logger = logger
+	}
+
+	handler := &Handler{
+		tasks: make(map[string]Task, len(options.Tasks)),
+		names: make([]string, 0, len(options.Tasks)),
+
+		timeKeeper: options.TimeKeeper,
+		authorizer: options.Authorizer,
+
+		logger: options.Logger,
+	}
+
+	for _, task := range options.Tasks {
+		if _, ok := handler.tasks[task.Name()]; ok {
+			return nil, fmt.Errorf("duplicate task %q", task.Name())
+		}
+
+		handler.tasks[task.Name()] = task
+		handler.names = append(handler.names, task.Name())
+	}
+
+	sort.Strings(handler.names)
+
+	return handler, nil
+}
+
+// NewHandler creates a new Handler instance, or errors out if
+// several tasks share the same name, or no authorizer is set.
+func NewHandler(setters ...Option) (*Handler, error) {
+	// Default Options
+	args := &Options{
+		Logger: logrus.StandardLogger(),
+	}
+
+	for _, setter := range setters {
+		setter(args)
+	}
+
+	return NewHandlerWithOptions(args)
+}
+
+// errorResponse is the body of all error responses.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// httpError is an error with a status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e httpError) Unwrap() error {
+	return e.err
+}
+
+func newHTTPError(status int, format string, args ...interface{}) error {
+	return httpError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+// route is a single endpoint of the handler.
+type route struct {
+	method  string
+	action  Action
+	handler func(ctx context.Context, name string, query url.Values) (int, interface{}, error)
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		handler.respondError(w, newHTTPError(http.StatusBadRequest, "malformed path: %s", err))
+		return
+	}
+
+	route, name, ok := handler.route(segments)
+	if !ok {
+		handler.respondError(w, newHTTPError(http.StatusNotFound, "unknown endpoint %s", r.URL.Path))
+		return
+	}
+
+	if r.Method != route.method {
+		w.Header().Set("Allow", route.method)
+		handler.respondError(w, newHTTPError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+
+	if err := handler.authorizer(r, route.action); errors.Is(err, ErrUnauthenticated) {
+		handler.respondError(w, httpError{status: http.StatusUnauthorized, err: err})
+		return
+	} else if err != nil {
+		handler.respondError(w, httpError{status: http.StatusForbidden, err: err})
+		return
+	}
+
+	status, body, err := route.handler(r.Context(), name, r.URL.Query())
+	if err != nil {
+		handler.respondError(w, err)
+		return
+	}
+
+	handler.respond(w, status, body)
+}
+
+// route resolves the route and task name of the given path segments.
+func (handler *Handler) route(segments []string) (route, string, bool) {
+	switch {
+	case len(segments) == 1 && segments[0] == "tasks":
+		return route{http.MethodGet, ActionRead, handler.listTasks}, "", true
+	case len(segments) == 2 && segments[0] == "tasks":
+		return route{http.MethodGet, ActionRead, handler.getTask}, segments[1], true
+	case len(segments) == 3 && segments[0] == "tasks" && segments[2] == "trigger":
+		return route{http.MethodPost, ActionTrigger, handler.triggerTask}, segments[1], true
+	case len(segments) == 3 && segments[0] == "tasks" && segments[2] == "pause":
+		return route{http.MethodPost, ActionPause, handler.pauseTask}, segments[1], true
+	case len(segments) == 3 && segments[0] == "tasks" && segments[2] == "resume":
+		return route{http.MethodPost, ActionResume, handler.resumeTask}, segments[1], true
+	case len(segments) == 1 && segments[0] == "runs":
+		return route{http.MethodGet, ActionRead, handler.listRuns}, "", true
+	case len(segments) == 2 && segments[0] == "runs" && segments[1] == "last":
+		return route{http.MethodGet, ActionRead, handler.listLastRuns}, "", true
+	case len(segments) == 3 && segments[0] == "runs" && segments[1] == "last":
+		return route{http.MethodGet, ActionRead, handler.getLastRun}, segments[2], true
+	default:
+		return route{}, "", false
+	}
+}
+
+// pathSegments returns the unescaped segments of the path, so
+// task names may contain escaped slashes.
+func pathSegments(u *url.URL) ([]string, error) {
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		segments[i] = unescaped
+	}
+
+	return segments, nil
+}
+
+// taskView is the JSON representation of a task.
+type taskView struct {
+	crontask.TaskState
+
+	NextTime time.Time `json:"nextTime"`
+	Paused   bool      `json:"paused"`
+}
+
+func (handler *Handler) task(name string) (Task, error) {
+	task, ok := handler.tasks[name]
+	if !ok {
+		return nil, newHTTPError(http.StatusNotFound, "unknown task %q", name)
+	}
+
+	return task, nil
+}
+
+func (handler *Handler) taskView(ctx context.Context, task Task) (taskView, error) {
+	state, err := task.State(ctx)
+	if err != nil {
+		return taskView{}, fmt.Errorf("failed to inspect task %q: %w", task.Name(), err)
+	}
+
+	return taskView{
+		TaskState: state,
+		NextTime:  task.NextTime(),
+		Paused:    state.Paused(),
+	}, nil
+}
+
+func (handler *Handler) listTasks(ctx context.Context, _ string, _ url.Values) (int, interface{}, error) {
+	views := make([]taskView, len(handler.names))
+	for i, name := range handler.names {
+		view, err := handler.taskView(ctx, handler.tasks[name])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		views[i] = view
+	}
+
+	return http.StatusOK, views, nil
+}
+
+func (handler *Handler) getTask(ctx context.Context, name string, _ url.Values) (int, interface{}, error) {
+	task, err := handler.task(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	view, err := handler.taskView(ctx, task)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, view, nil
+}
+
+func (handler *Handler) triggerTask(_ context.Context, name string, _ url.Values) (int, interface{}, error) {
+	task, err := handler.task(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	handler.logger.Infof("Triggering synchronized task %q via admin handler", name)
+
+	// The execution outlives the request, so its outcome - including whether
+	// it was skipped - is only logged by the task and recorded by the time keeper
+	go task.ExecuteNow()
+
+	return http.StatusAccepted, struct {
+		Name     string `json:"name"`
+		Accepted bool   `json:"accepted"`
+	}{name, true}, nil
+}
+
+func (handler *Handler) pauseTask(ctx context.Context, name string, _ url.Values) (int, interface{}, error) {
+	task, err := handler.task(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := task.Pause(ctx); err != nil {
+		return 0, nil, fmt.Errorf("failed to pause task %q: %w", name, err)
+	}
+
+	handler.logger.Infof("Paused synchronized task %q via admin handler", name)
+
+	return handler.getTask(ctx, name, nil)
+}
+
+func (handler *Handler) resumeTask(ctx context.Context, name string, _ url.Values) (int, interface{}, error) {
+	task, err := handler.task(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := task.Resume(ctx); err != nil {
+		return 0, nil, fmt.Errorf("failed to resume task %q: %w", name, err)
+	}
+
+	handler.logger.Infof("Resumed synchronized task %q via admin handler", name)
+
+	return handler.getTask(ctx, name, nil)
+}
+
+func (handler *Handler) requireTimeKeeper() error {
+	if handler.timeKeeper == nil {
+		return newHTTPError(http.StatusNotFound, "no time keeper configured")
+	}
+
+	return nil
+}
+
+func (handler *Handler) listRuns(ctx context.Context, _ string, query url.Values) (int, interface{}, error) {
+	if err := handler.requireTimeKeeper(); err != nil {
+		return 0, nil, err
+	}
+
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil || offset < 0 {
+		return 0, nil, newHTTPError(http.StatusBadRequest, "offset must be a non-negative integer")
+	}
+
+	limit, err := queryInt(query, "limit", DefaultRunsLimit)
+	if err != nil || limit <= 0 || limit > MaxRunsLimit {
+		return 0, nil, newHTTPError(http.StatusBadRequest, "limit must be an integer between 1 and %d", MaxRunsLimit)
+	}
+
+	total, err := handler.timeKeeper.CountAllRuns(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	results, err := handler.timeKeeper.GetAllRuns(ctx, offset, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	return http.StatusOK, struct {
+		Total  int64                `json:"total"`
+		Offset int64                `json:"offset"`
+		Limit  int64                `json:"limit"`
+		Runs   []timekeeper.RunView `json:"runs"`
+	}{total, offset, limit, timekeeper.NewRunViews(results...)}, nil
+}
+
+func (handler *Handler) listLastRuns(ctx context.Context, _ string, _ url.Values) (int, interface{}, error) {
+	if err := handler.requireTimeKeeper(); err != nil {
+		return 0, nil, err
+	}
+
+	results, err := handler.timeKeeper.GetLastRunOfAllTasks(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list last runs: %w", err)
+	}
+
+	return http.StatusOK, timekeeper.NewRunViews(results...), nil
+}
+
+func (handler *Handler) getLastRun(ctx context.Context, name string, _ url.Values) (int, interface{}, error) {
+	if err := handler.requireTimeKeeper(); err != nil {
+		return 0, nil, err
+	}
+
+	result, err := handler.timeKeeper.GetLastRunOfTask(ctx, name)
+	if errors.Is(err, redis.Nil) {
+		return 0, nil, newHTTPError(http.StatusNotFound, "no run of task %q is recorded", name)
+	} else if err != nil {
+		return 0, nil, fmt.Errorf("failed to get last run of task %q: %w", name, err)
+	}
+
+	return http.StatusOK, timekeeper.NewRunViews(result)[0], nil
+}
+
+func queryInt(query url.Values, key string, defaultValue int64) (int64, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// respondError responds with the given error. Errors without a status are
+// internal ones, which are only logged - as they might expose internals,
+// such as redis addresses.
+func (handler *Handler) respondError(w http.ResponseWriter, err error) {
+	var target httpError
+	if !errors.As(err, &target) {
+		handler.logger.Errorf("Admin handler request failed: %s", err)
+		handler.respond(w, http.StatusInternalServerError, errorResponse{Error: http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+
+	handler.respond(w, target.status, errorResponse{Error: err.Error()})
+}
+
+func (handler *Handler) respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		handler.logger.Warnf("Failed to write admin handler response: %s", err)
+	}
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Options bundles all available configuration
+// properties for an admin handler.
+type Options struct {
+	Tasks      []Task
+	TimeKeeper RunHistory
+
+	Authorizer Authorizer
+
+	Logger *logrus.Logger
+}
+
+// Option represents an option for an admin handler.
+type Option func(*Options)
+
+// Tasks adds tasks to the handler. Task names must be unique.
+// The default is no tasks.
+func Tasks(tasks ...Task) Option {
+	return func(c *Options) {
+		c.Tasks = append(c.Tasks, tasks...)
+	}
+}
+
+// TimeKeeper sets the time keeper, whose recorded runs are exposed by the handler.
+// The default is nil, which disables all endpoints for runs.
+func TimeKeeper(timeKeeper RunHistory) Option {
+	return func(c *Options) {
+		c.TimeKeeper = timeKeeper
+	}
+}
+
+// Authorization sets the authorizer, which decides about every request. It is
+// required - use AllowAll, if the handler is protected otherwise.
+// The default is nil, which fails the creation of the handler.
+func Authorization(authorizer Authorizer) Option {
+	return func(c *Options) {
+		c.Authorizer = authorizer
+	}
+}
+
+// Logger sets the logger of the handler.
+// The default is the logrus global default logger.
+func Logger(logger *logrus.Logger) Option {
+	return func(c *Options) {
+		c.Logger = logger
+	}
+}
@@ -0,0 +1,82 @@
+package admin_test
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/admin"
+
+	"github.com/sirupsen/logrus"
+
+	"net/http"
+	"testing"
+)
+
+// Tests that the Tasks option correctly applies.
+func Test_Option_Tasks(t *testing.T) {
+	// given
+	first, second := newFakeTask("first"), newFakeTask("second")
+	option := admin.Tasks(second)
+	options := &admin.Options{Tasks: []admin.Task{first}}
+
+	// when
+	option(options)
+
+	// then
+	if len(options.Tasks) != 2 || options.Tasks[0] != first || options.Tasks[1] != second {
+		t.Errorf("tasks not correctly applied, got %v", options.Tasks)
+	}
+}
+
+// Tests that the TimeKeeper option correctly applies.
+func Test_Option_TimeKeeper(t *testing.T) {
+	// given
+	expected := &fakeRunHistory{}
+	option := admin.TimeKeeper(expected)
+	options := &admin.Options{TimeKeeper: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.TimeKeeper != expected {
+		t.Errorf("time keeper not correctly applied, got %v", options.TimeKeeper)
+	}
+}
+
+// Tests that the Authorization option correctly applies.
+func Test_Option_Authorization(t *testing.T) {
+	// given
+	called := false
+	option := admin.Authorization(func(*http.Request, admin.Action) error {
+		called = true
+		return nil
+	})
+	options := &admin.Options{Authorizer: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Authorizer == nil {
+		t.Fatal("authorizer not correctly applied, got nil")
+	}
+
+	_ = options.Authorizer(nil, admin.ActionRead)
+	if !called {
+		t.Error("authorizer not correctly applied, applied authorizer was not called")
+	}
+}
+
+// Tests that the Logger option correctly applies.
+func Test_Option_Logger(t *testing.T) {
+	// given
+	expected := logrus.New()
+	option := admin.Logger(expected)
+	options := &admin.Options{Logger: logrus.StandardLogger()}
+
+	// when
+	option(options)
+
+	// then
+	if options.Logger != expected {
+		t.Errorf("logger not correctly applied, got %v", options.Logger)
+	}
+}
@@ -0,0 +1,615 @@
+package admin_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/admin"
+	"github.com/kernle32dll/synchronized-cron-task/crontasktest"
+	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
+
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTask is an in-memory admin.Task.
+type fakeTask struct {
+	name     string
+	nextTime time.Time
+
+	mutex       sync.Mutex
+	pausedSince time.Time
+	triggered   chan struct{}
+}
+
+func newFakeTask(name string) *fakeTask {
+	return &fakeTask{
+		name:      name,
+		nextTime:  time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC),
+		triggered: make(chan struct{}, 1),
+	}
+}
+
+func (task *fakeTask) Name() string        { return task.name }
+func (task *fakeTask) NextTime() time.Time { return task.nextTime }
+func (task *fakeTask) ExecuteNow()         { task.triggered <- struct{}{} }
+
+func (task *fakeTask) Pause(context.Context) error {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+
+	task.pausedSince = time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	return nil
+}
+
+func (task *fakeTask) Resume(context.Context) error {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+
+	task.pausedSince = time.Time{}
+	return nil
+}
+
+func (task *fakeTask) State(context.Context) (crontask.TaskState, error) {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+
+	return crontask.TaskState{
+		Name:        task.name,
+		Locked:      true,
+		Holder:      "some-instance",
+		LockTTL:     5 * time.Second,
+		PausedSince: task.pausedSince,
+	}, nil
+}
+
+// fakeRunHistory is an in-memory admin.RunHistory.
+type fakeRunHistory struct {
+	runs []timekeeper.ExecutionResult
+	err  error
+}
+
+func (history *fakeRunHistory) GetAllRuns(_ context.Context, offset, limit int64) ([]timekeeper.ExecutionResult, error) {
+	end := offset + limit
+	if end > int64(len(history.runs)) {
+		end = int64(len(history.runs))
+	}
+
+	if offset >= end {
+		return []timekeeper.ExecutionResult{}, nil
+	}
+
+	return history.runs[offset:end], nil
+}
+
+func (history *fakeRunHistory) CountAllRuns(context.Context) (int64, error) {
+	return int64(len(history.runs)), history.err
+}
+
+func (history *fakeRunHistory) GetLastRunOfAllTasks(context.Context) ([]timekeeper.ExecutionResult, error) {
+	return history.runs[:1], nil
+}
+
+func (history *fakeRunHistory) GetLastRunOfTask(_ context.Context, name string) (timekeeper.ExecutionResult, error) {
+	for _, run := range history.runs {
+		if run.Name == name {
+			return run, nil
+		}
+	}
+
+	return timekeeper.ExecutionResult{}, redis.Nil
+}
+
+func newTestHandler(t *testing.T, setters ...admin.Option) (*admin.Handler, *fakeTask) {
+	task := newFakeTask("some task/with slash")
+
+	history := &fakeRunHistory{runs: []timekeeper.ExecutionResult{
+		{Name: "some task/with slash", LastExecution: time.Date(2026, time.October, 2, 3, 0, 0, 0, time.UTC), LastDuration: time.Second, Error: errors.New("some error"), Result: &crontask.TaskResult{Summary: "processed 1234 rows"}},
+		{Name: "other-task", LastExecution: time.Date(2026, time.October, 1, 3, 0, 0, 0, time.UTC), LastDuration: time.Minute},
+	}}
+
+	handler, err := admin.NewHandler(append([]admin.Option{
+		admin.Tasks(task, newFakeTask("other-task")),
+		admin.TimeKeeper(history),
+		admin.Authorization(admin.AllowAll()),
+		admin.Logger(nil),
+	}, setters...)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return handler, task
+}
+
+func serve(handler http.Handler, method string, target string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, expectedStatus int, target interface{}) {
+	t.Helper()
+
+	if recorder.Code != expectedStatus {
+		t.Fatalf("expected status %d, got %d: %s", expectedStatus, recorder.Code, recorder.Body.String())
+	}
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected JSON content type, got %q", contentType)
+	}
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), target); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+type taskResponse struct {
+	Name        string    `json:"name"`
+	NextTime    time.Time `json:"nextTime"`
+	Locked      bool      `json:"locked"`
+	Holder      string    `json:"holder"`
+	Paused      bool      `json:"paused"`
+	PausedSince time.Time `json:"pausedSince"`
+}
+
+type runResponse struct {
+	Name          string               `json:"name"`
+	LastExecution time.Time            `json:"lastExecution"`
+	LastDuration  time.Duration        `json:"lastDuration"`
+	Error         string               `json:"error"`
+	Result        *crontask.TaskResult `json:"result"`
+}
+
+func Test_Handler(t *testing.T) {
+	t.Run("duplicate-tasks", duplicateTasksTest)
+
+	t.Run("authorizer-required", authorizerRequiredTest)
+
+	t.Run("list-tasks", listTasksTest)
+
+	t.Run("get-task", getTaskTest)
+
+	t.Run("unknown-task", unknownTaskTest)
+
+	t.Run("trigger", triggerTest)
+
+	t.Run("trigger-skipped", triggerSkippedTest)
+
+	t.Run("pause-resume", pauseResumeTest)
+
+	t.Run("list-runs", listRunsTest)
+
+	t.Run("invalid-pagination", invalidPaginationTest)
+
+	t.Run("last-runs", lastRunsTest)
+
+	t.Run("no-time-keeper", noTimeKeeperTest)
+
+	t.Run("internal-error", internalErrorTest)
+
+	t.Run("routing", routingTest)
+
+	t.Run("authorization", authorizationTest)
+
+	t.Run("bearer-token", bearerTokenTest)
+}
+
+func duplicateTasksTest(t *testing.T) {
+	// given
+	// when
+	_, err := admin.NewHandler(
+		admin.Tasks(newFakeTask("some-task"), newFakeTask("some-task")),
+		admin.Authorization(admin.AllowAll()),
+	)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), `duplicate task "some-task"`) {
+		t.Errorf("expected duplicate task error, got %v", err)
+	}
+}
+
+func authorizerRequiredTest(t *testing.T) {
+	// given
+	// when
+	handler, err := admin.NewHandler(admin.Tasks(newFakeTask("some-task")))
+
+	// then
+	if !errors.Is(err, admin.ErrNoAuthorizer) {
+		t.Errorf("expected missing authorizer error, got %v", err)
+	}
+
+	if handler != nil {
+		t.Error("expected no handler being returned, but was")
+	}
+}
+
+func listTasksTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	// when
+	recorder := serve(handler, http.MethodGet, "/tasks", nil)
+
+	// then
+	var tasks []taskResponse
+	decode(t, recorder, http.StatusOK, &tasks)
+
+	if len(tasks) != 2 || tasks[0].Name != "other-task" || tasks[1].Name != "some task/with slash" {
+		t.Fatalf("expected tasks sorted by name, got %+v", tasks)
+	}
+
+	if !tasks[0].Locked || tasks[0].Holder != "some-instance" || tasks[0].NextTime.IsZero() {
+		t.Errorf("expected state and next time of task, got %+v", tasks[0])
+	}
+}
+
+func getTaskTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	// when
+	recorder := serve(handler, http.MethodGet, "/tasks/some%20task%2Fwith%20slash", nil)
+
+	// then
+	var task taskResponse
+	decode(t, recorder, http.StatusOK, &task)
+
+	if task.Name != "some task/with slash" {
+		t.Errorf("expected escaped task name to be resolved, got %q", task.Name)
+	}
+}
+
+func unknownTaskTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	for _, request := range []struct{ method, target string }{
+		{http.MethodGet, "/tasks/unknown"},
+		{http.MethodPost, "/tasks/unknown/trigger"},
+		{http.MethodPost, "/tasks/unknown/pause"},
+		{http.MethodPost, "/tasks/unknown/resume"},
+	} {
+		// when
+		recorder := serve(handler, request.method, request.target, nil)
+
+		// then
+		var body struct {
+			Error string `json:"error"`
+		}
+		decode(t, recorder, http.StatusNotFound, &body)
+
+		if body.Error != `unknown task "unknown"` {
+			t.Errorf("unexpected error %q for %s %s", body.Error, request.method, request.target)
+		}
+	}
+}
+
+func triggerTest(t *testing.T) {
+	// given
+	handler, task := newTestHandler(t)
+
+	// when
+	recorder := serve(handler, http.MethodPost, "/tasks/some%20task%2Fwith%20slash/trigger", nil)
+
+	// then
+	var body struct {
+		Name     string `json:"name"`
+		Accepted bool   `json:"accepted"`
+	}
+	decode(t, recorder, http.StatusAccepted, &body)
+
+	if !body.Accepted || body.Name != task.Name() {
+		t.Errorf("unexpected response %+v", body)
+	}
+
+	select {
+	case <-task.triggered:
+	case <-time.After(time.Second):
+		t.Error("expected task to be executed")
+	}
+}
+
+// Tests that triggers are accepted, even if the execution is skipped - as
+// another instance holds the lock of the task.
+func triggerSkippedTest(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	instances := harness.StartInstances(2,
+		func(ctx context.Context, task crontask.Task) error {
+			<-ctx.Done()
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+	)
+
+	harness.Advance(time.Minute)
+
+	executions := harness.Executions(crontask.DefaultName)
+	if len(executions) != 1 {
+		t.Fatalf("expected one running execution, got %d", len(executions))
+	}
+
+	idle := instances[1-executions[0].Instance]
+	handler, err := admin.NewHandler(
+		admin.Tasks(idle.Task),
+		admin.Authorization(admin.AllowAll()),
+		admin.Logger(nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// when
+	recorder := serve(handler, http.MethodPost, "/tasks/"+url.PathEscape(crontask.DefaultName)+"/trigger", nil)
+	harness.Settle()
+
+	// then
+	var body struct {
+		Name     string `json:"name"`
+		Accepted bool   `json:"accepted"`
+	}
+	decode(t, recorder, http.StatusAccepted, &body)
+
+	if !body.Accepted || body.Name != crontask.DefaultName {
+		t.Errorf("unexpected response %+v", body)
+	}
+
+	if executions := harness.Executions(crontask.DefaultName); len(executions) != 1 {
+		t.Errorf("expected trigger to be skipped, got %d executions", len(executions))
+	}
+}
+
+func pauseResumeTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	// when
+	paused := serve(handler, http.MethodPost, "/tasks/other-task/pause", nil)
+	resumed := serve(handler, http.MethodPost, "/tasks/other-task/resume", nil)
+
+	// then
+	var pausedTask, resumedTask taskResponse
+	decode(t, paused, http.StatusOK, &pausedTask)
+	decode(t, resumed, http.StatusOK, &resumedTask)
+
+	if !pausedTask.Paused || pausedTask.PausedSince.IsZero() {
+		t.Errorf("expected task to be paused, got %+v", pausedTask)
+	}
+
+	if resumedTask.Paused {
+		t.Errorf("expected task to be resumed, got %+v", resumedTask)
+	}
+}
+
+func listRunsTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	// when
+	recorder := serve(handler, http.MethodGet, "/runs?offset=1&limit=5", nil)
+
+	// then
+	var body struct {
+		Total  int64         `json:"total"`
+		Offset int64         `json:"offset"`
+		Limit  int64         `json:"limit"`
+		Runs   []runResponse `json:"runs"`
+	}
+	decode(t, recorder, http.StatusOK, &body)
+
+	if body.Total != 2 || body.Offset != 1 || body.Limit != 5 {
+		t.Errorf("unexpected pagination %+v", body)
+	}
+
+	if len(body.Runs) != 1 || body.Runs[0].Name != "other-task" || body.Runs[0].LastDuration != time.Minute {
+		t.Errorf("unexpected runs %+v", body.Runs)
+	}
+}
+
+func invalidPaginationTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	for _, target := range []string{"/runs?offset=-1", "/runs?offset=a", "/runs?limit=0", "/runs?limit=1001"} {
+		// when
+		recorder := serve(handler, http.MethodGet, target, nil)
+
+		// then
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for %s, got %d", http.StatusBadRequest, target, recorder.Code)
+		}
+	}
+}
+
+func lastRunsTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+
+	// when
+	all := serve(handler, http.MethodGet, "/runs/last", nil)
+	single := serve(handler, http.MethodGet, "/runs/last/some%20task%2Fwith%20slash", nil)
+	missing := serve(handler, http.MethodGet, "/runs/last/unknown", nil)
+
+	// then
+	var allRuns []runResponse
+	decode(t, all, http.StatusOK, &allRuns)
+
+	if len(allRuns) != 1 {
+		t.Errorf("expected one run, got %+v", allRuns)
+	}
+
+	var singleRun runResponse
+	decode(t, single, http.StatusOK, &singleRun)
+
+	if singleRun.Name != "some task/with slash" || singleRun.Error != "some error" {
+		t.Errorf("unexpected run %+v", singleRun)
+	}
+
+	if singleRun.Result == nil || singleRun.Result.Summary != "processed 1234 rows" {
+		t.Errorf("unexpected run result %+v", singleRun.Result)
+	}
+
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for missing run, got %d", http.StatusNotFound, missing.Code)
+	}
+}
+
+func noTimeKeeperTest(t *testing.T) {
+	// given
+	handler, err := admin.NewHandler(admin.Authorization(admin.AllowAll()), admin.Logger(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, target := range []string{"/runs", "/runs/last", "/runs/last/some-task"} {
+		// when
+		recorder := serve(handler, http.MethodGet, target, nil)
+
+		// then
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for %s, got %d", http.StatusNotFound, target, recorder.Code)
+		}
+	}
+}
+
+func internalErrorTest(t *testing.T) {
+	// given
+	history := &fakeRunHistory{err: errors.New("dial tcp 10.0.0.1:6379: connection refused")}
+	handler, err := admin.NewHandler(
+		admin.TimeKeeper(history),
+		admin.Authorization(admin.AllowAll()),
+		admin.Logger(nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// when
+	recorder := serve(handler, http.MethodGet, "/runs", nil)
+
+	// then
+	var body struct {
+		Error string `json:"error"`
+	}
+	decode(t, recorder, http.StatusInternalServerError, &body)
+
+	if body.Error != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expected generic error message, got %q", body.Error)
+	}
+}
+
+func routingTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t)
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", handler))
+
+	// when
+	mounted := serve(mux, http.MethodGet, "/admin/tasks/other-task", nil)
+	unknown := serve(handler, http.MethodGet, "/tasks/other-task/explode", nil)
+	wrongMethod := serve(handler, http.MethodGet, "/tasks/other-task/trigger", nil)
+
+	// then
+	if mounted.Code != http.StatusOK {
+		t.Errorf("expected status %d for mounted handler, got %d", http.StatusOK, mounted.Code)
+	}
+
+	if unknown.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown endpoint, got %d", http.StatusNotFound, unknown.Code)
+	}
+
+	if wrongMethod.Code != http.StatusMethodNotAllowed || wrongMethod.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("expected status %d allowing POST, got %d", http.StatusMethodNotAllowed, wrongMethod.Code)
+	}
+}
+
+func authorizationTest(t *testing.T) {
+	// given
+	var actions []admin.Action
+	handler, task := newTestHandler(t, admin.Authorization(func(r *http.Request, action admin.Action) error {
+		actions = append(actions, action)
+
+		if action != admin.ActionRead {
+			return errors.New("read-only access")
+		}
+
+		return nil
+	}))
+
+	// when
+	read := serve(handler, http.MethodGet, "/tasks", nil)
+	trigger := serve(handler, http.MethodPost, "/tasks/other-task/trigger", nil)
+	pause := serve(handler, http.MethodPost, "/tasks/other-task/pause", nil)
+
+	// then
+	if read.Code != http.StatusOK {
+		t.Errorf("expected status %d for read, got %d", http.StatusOK, read.Code)
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	decode(t, trigger, http.StatusForbidden, &body)
+
+	if body.Error != "read-only access" {
+		t.Errorf("expected reason of authorizer, got %q", body.Error)
+	}
+
+	if pause.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for pause, got %d", http.StatusForbidden, pause.Code)
+	}
+
+	expected := []admin.Action{admin.ActionRead, admin.ActionTrigger, admin.ActionPause}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected actions %v, got %v", expected, actions)
+	}
+
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Errorf("expected actions %v, got %v", expected, actions)
+		}
+	}
+
+	select {
+	case <-task.triggered:
+		t.Error("expected unauthorized trigger not to execute task")
+	default:
+	}
+}
+
+func bearerTokenTest(t *testing.T) {
+	// given
+	handler, _ := newTestHandler(t, admin.Authorization(admin.BearerToken("secret")))
+
+	// when
+	missing := serve(handler, http.MethodGet, "/tasks", nil)
+	wrong := serve(handler, http.MethodGet, "/tasks", http.Header{"Authorization": {"Bearer guess"}})
+	valid := serve(handler, http.MethodGet, "/tasks", http.Header{"Authorization": {"Bearer secret"}})
+
+	// then
+	if missing.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without token, got %d", http.StatusUnauthorized, missing.Code)
+	}
+
+	if wrong.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for wrong token, got %d", http.StatusUnauthorized, wrong.Code)
+	}
+
+	if valid.Code != http.StatusOK {
+		t.Errorf("expected status %d for valid token, got %d", http.StatusOK, valid.Code)
+	}
+}
@@ -0,0 +1,406 @@
+package crontask
+
+import (
+	"github.com/robfig/cron/v3"
+
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxCalendarSkips is the maximum amount of excluded spans skipped, while
+// searching for the next activation of a schedule wrapped by a calendar.
+const maxCalendarSkips = 1000
+
+// Exclusion describes spans of time, in which a schedule must not fire.
+type Exclusion interface {
+	// Excludes returns true, if the given time is excluded. If so, the
+	// (exclusive) end of the excluded span containing the time is returned.
+	Excludes(t time.Time) (bool, time.Time)
+}
+
+// Calendar bundles exclusions, such as public holidays or freeze windows,
+// which are skipped by the schedule of a synchronized cron task.
+type Calendar struct {
+	location   *time.Location
+	exclusions []Exclusion
+}
+
+// NewCalendar creates a new Calendar. Recurring exclusions and dates are
+// evaluated in the given location, which defaults to UTC if nil.
+func NewCalendar(location *time.Location, exclusions ...Exclusion) *Calendar {
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &Calendar{
+		location:   location,
+		exclusions: exclusions,
+	}
+}
+
+// Excludes returns true, if the given time is excluded by any exclusion of
+// the calendar. If so, the (exclusive) end of the excluded span is returned.
+func (calendar *Calendar) Excludes(t time.Time) (bool, time.Time) {
+	t = t.In(calendar.location)
+
+	for _, exclusion := range calendar.exclusions {
+		if excluded, end := exclusion.Excludes(t); excluded {
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// Schedule wraps the given schedule, so that all activations falling
+// into an excluded span of the calendar are skipped.
+func (calendar *Calendar) Schedule(schedule cron.Schedule) cron.Schedule {
+	return calendarSchedule{
+		schedule: schedule,
+		calendar: calendar,
+	}
+}
+
+// calendarSchedule is a schedule, which skips activations excluded by a calendar.
+type calendarSchedule struct {
+	schedule cron.Schedule
+	calendar *Calendar
+}
+
+func (s calendarSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxCalendarSkips; i++ {
+		next := s.schedule.Next(t)
+		if next.IsZero() {
+			return next
+		}
+
+		excluded, end := s.calendar.Excludes(next)
+		if !excluded {
+			return next
+		}
+
+		// Continue right before the end of the excluded span, so
+		// an activation exactly at its end is not skipped.
+		t = next
+		if before := end.Add(-time.Nanosecond); before.After(t) {
+			t = before
+		}
+	}
+
+	return time.Time{}
+}
+
+// ExcludeDate excludes the whole given day.
+func ExcludeDate(year int, month time.Month, day int) Exclusion {
+	return dateExclusion{year: year, month: month, day: day}
+}
+
+type dateExclusion struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func (e dateExclusion) Excludes(t time.Time) (bool, time.Time) {
+	start := time.Date(e.year, e.month, e.day, 0, 0, 0, 0, t.Location())
+	return windowExclusion{from: start, to: start.AddDate(0, 0, 1)}.Excludes(t)
+}
+
+// ExcludeWindow excludes the span between the two given times. The
+// end of the window is exclusive.
+func ExcludeWindow(from time.Time, to time.Time) Exclusion {
+	return windowExclusion{from: from, to: to}
+}
+
+type windowExclusion struct {
+	from time.Time
+	to   time.Time
+}
+
+func (e windowExclusion) Excludes(t time.Time) (bool, time.Time) {
+	if !t.Before(e.from) && t.Before(e.to) {
+		return true, e.to
+	}
+
+	return false, time.Time{}
+}
+
+// ExcludeYearly excludes the given span of days every year. Both days
+// are inclusive. The span may wrap around the end of the year, e.g. from
+// the 24th of December to the 2nd of January.
+func ExcludeYearly(fromMonth time.Month, fromDay int, toMonth time.Month, toDay int) Exclusion {
+	return yearlyExclusion{fromMonth: fromMonth, fromDay: fromDay, toMonth: toMonth, toDay: toDay}
+}
+
+type yearlyExclusion struct {
+	fromMonth time.Month
+	fromDay   int
+	toMonth   time.Month
+	toDay     int
+}
+
+func (e yearlyExclusion) Excludes(t time.Time) (bool, time.Time) {
+	// Spans wrapping around the end of the year might have started in the previous year
+	for _, year := range []int{t.Year() - 1, t.Year()} {
+		start := time.Date(year, e.fromMonth, e.fromDay, 0, 0, 0, 0, t.Location())
+
+		end := time.Date(year, e.toMonth, e.toDay+1, 0, 0, 0, 0, t.Location())
+		if !end.After(start) {
+			end = end.AddDate(1, 0, 0)
+		}
+
+		if excluded, end := (windowExclusion{from: start, to: end}).Excludes(t); excluded {
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// ExcludeMonthly excludes the given span of days every month. Both days are
+// inclusive. Negative days count from the end of the month, e.g. -1 is the last
+// day of the month. Thus, a month-end freeze of the last three days of every
+// month can be described as ExcludeMonthly(-3, -1).
+func ExcludeMonthly(fromDay int, toDay int) Exclusion {
+	return monthlyExclusion{fromDay: fromDay, toDay: toDay}
+}
+
+type monthlyExclusion struct {
+	fromDay int
+	toDay   int
+}
+
+func (e monthlyExclusion) Excludes(t time.Time) (bool, time.Time) {
+	// Spans wrapping around the end of the month might have started in the previous month
+	for _, offset := range []int{-1, 0} {
+		year, month, _ := time.Date(t.Year(), t.Month()+time.Month(offset), 1, 0, 0, 0, 0, t.Location()).Date()
+
+		start := time.Date(year, month, resolveDay(year, month, e.fromDay), 0, 0, 0, 0, t.Location())
+
+		end := time.Date(year, month, resolveDay(year, month, e.toDay)+1, 0, 0, 0, 0, t.Location())
+		if !end.After(start) {
+			nextYear, nextMonth, _ := start.AddDate(0, 1, 1-start.Day()).Date()
+			end = time.Date(nextYear, nextMonth, resolveDay(nextYear, nextMonth, e.toDay)+1, 0, 0, 0, 0, t.Location())
+		}
+
+		if excluded, end := (windowExclusion{from: start, to: end}).Excludes(t); excluded {
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// resolveDay resolves negative days, counting from the end of the given month.
+func resolveDay(year int, month time.Month, day int) int {
+	if day >= 0 {
+		return day
+	}
+
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return daysInMonth + day + 1
+}
+
+// LoadICS reads all events of the given iCalendar file as exclusions.
+// See ParseICS for details.
+func LoadICS(path string, location *time.Location) ([]Exclusion, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseICS(file, location)
+}
+
+// ParseICS reads all events of an iCalendar (RFC 5545) document as exclusions.
+// Dates and floating times are interpreted in the given location, which defaults
+// to UTC if nil. Of recurrence rules, only yearly recurrence is supported, as
+// commonly used for public holidays.
+func ParseICS(reader io.Reader, location *time.Location) ([]Exclusion, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	lines, err := unfoldICSLines(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var exclusions []Exclusion
+	var event *icsExclusion
+	for i, line := range lines {
+		name, params, value, err := parseICSLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icsExclusion{}
+		case name == "END" && value == "VEVENT" && event != nil:
+			if event.from.IsZero() {
+				return nil, fmt.Errorf("line %d: event without DTSTART", i+1)
+			}
+
+			if event.to.IsZero() {
+				// Events without an end last one day, if they start at a
+				// date. Otherwise, they are instantaneous (RFC 5545 3.6.1).
+				event.to = event.from
+				if event.allDay {
+					event.to = event.from.AddDate(0, 0, 1)
+				}
+			}
+
+			exclusions = append(exclusions, *event)
+			event = nil
+		case event == nil:
+			continue
+		case name == "DTSTART":
+			event.from, event.allDay, err = parseICSTime(params, value, location)
+		case name == "DTEND":
+			event.to, _, err = parseICSTime(params, value, location)
+		case name == "RRULE":
+			event.yearly, event.until, err = parseICSRecurrence(value, location)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	return exclusions, nil
+}
+
+type icsExclusion struct {
+	from   time.Time
+	to     time.Time
+	allDay bool
+
+	yearly bool
+	until  time.Time
+}
+
+func (e icsExclusion) Excludes(t time.Time) (bool, time.Time) {
+	if !e.yearly {
+		return windowExclusion{from: e.from, to: e.to}.Excludes(t)
+	}
+
+	// Recurring events might have started in the previous year
+	for _, year := range []int{t.Year() - 1, t.Year()} {
+		from := e.from.AddDate(year-e.from.Year(), 0, 0)
+		if from.Before(e.from) || (!e.until.IsZero() && from.After(e.until)) {
+			continue
+		}
+
+		to := e.to.AddDate(year-e.from.Year(), 0, 0)
+		if excluded, end := (windowExclusion{from: from, to: to}).Excludes(t); excluded {
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// unfoldICSLines reads all content lines, joining folded lines (RFC 5545 3.1).
+func unfoldICSLines(reader io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseICSLine(line string) (string, map[string]string, string, error) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("malformed content line %q", line)
+	}
+
+	nameAndParams := strings.Split(line[:colon], ";")
+
+	params := make(map[string]string, len(nameAndParams)-1)
+	for _, param := range nameAndParams[1:] {
+		if keyAndValue := strings.SplitN(param, "=", 2); len(keyAndValue) == 2 {
+			params[strings.ToUpper(keyAndValue[0])] = strings.Trim(keyAndValue[1], `"`)
+		}
+	}
+
+	return strings.ToUpper(nameAndParams[0]), params, line[colon+1:], nil
+}
+
+func parseICSTime(params map[string]string, value string, location *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	if tzid, ok := params["TZID"]; ok {
+		tzLocation, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		location = tzLocation
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+func parseICSRecurrence(value string, location *time.Location) (bool, time.Time, error) {
+	var until time.Time
+	yearly := false
+
+	for _, part := range strings.Split(value, ";") {
+		keyAndValue := strings.SplitN(part, "=", 2)
+		if len(keyAndValue) != 2 {
+			return false, time.Time{}, fmt.Errorf("malformed recurrence rule part %q", part)
+		}
+
+		key, partValue := keyAndValue[0], keyAndValue[1]
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			if strings.ToUpper(partValue) != "YEARLY" {
+				return false, time.Time{}, fmt.Errorf("unsupported recurrence frequency %q", partValue)
+			}
+
+			yearly = true
+		case "UNTIL":
+			t, _, err := parseICSTime(nil, partValue, location)
+			if err != nil {
+				return false, time.Time{}, err
+			}
+
+			until = t
+		case "INTERVAL":
+			if partValue != "1" {
+				return false, time.Time{}, fmt.Errorf("unsupported recurrence interval %q", partValue)
+			}
+		default:
+			return false, time.Time{}, fmt.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+
+	return yearly, until, nil
+}
@@ -0,0 +1,173 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"github.com/robfig/cron/v3"
+
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Calendar(t *testing.T) {
+	t.Run("static-dates", calendarStaticDatesTest)
+
+	t.Run("recurring-ranges", calendarRecurringRangesTest)
+
+	t.Run("ics", calendarICSTest)
+
+	t.Run("malformed-ics", calendarMalformedICSTest)
+
+	t.Run("next-time", calendarNextTimeTest)
+}
+
+func calendarStaticDatesTest(t *testing.T) {
+	// given
+	calendar := crontask.NewCalendar(
+		nil,
+		crontask.ExcludeDate(2026, time.December, 25),
+		crontask.ExcludeWindow(
+			time.Date(2026, time.December, 27, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, time.December, 28, 12, 0, 0, 0, time.UTC),
+		),
+	)
+
+	// when
+	schedule := calendar.Schedule(mustParse(t, "0 0 * * *"))
+
+	// then
+	assertNextActivations(t, schedule, time.Date(2026, time.December, 24, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, time.December, 26, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.December, 27, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.December, 29, 0, 0, 0, 0, time.UTC),
+	)
+}
+
+func calendarRecurringRangesTest(t *testing.T) {
+	// given
+	calendar := crontask.NewCalendar(
+		nil,
+		crontask.ExcludeMonthly(-3, -1),
+		crontask.ExcludeYearly(time.December, 31, time.January, 2),
+	)
+
+	// when
+	schedule := calendar.Schedule(mustParse(t, "0 0 * * *"))
+
+	// then
+	assertNextActivations(t, schedule, time.Date(2026, time.February, 25, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+	)
+
+	assertNextActivations(t, schedule, time.Date(2026, time.December, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(2027, time.January, 3, 0, 0, 0, 0, time.UTC),
+	)
+}
+
+func calendarICSTest(t *testing.T) {
+	// given
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Labour Day",
+		"DTSTART;VALUE=DATE:20250501",
+		"RRULE:FREQ=YEARLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Maintenance",
+		"DTSTART:20260503T220000Z",
+		"DTEND:20260504T",
+		" 020000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	// when
+	exclusions, err := crontask.ParseICS(strings.NewReader(ics), nil)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error while parsing ics: %s", err)
+	}
+
+	if len(exclusions) != 2 {
+		t.Fatalf("expected 2 exclusions, got %d", len(exclusions))
+	}
+
+	schedule := crontask.NewCalendar(nil, exclusions...).Schedule(mustParse(t, "0 0 * * *"))
+	assertNextActivations(t, schedule, time.Date(2026, time.April, 30, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.May, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.May, 5, 0, 0, 0, 0, time.UTC),
+	)
+}
+
+func calendarMalformedICSTest(t *testing.T) {
+	// given
+	ics := strings.Join([]string{
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20250501",
+		"RRULE:FREQ=WEEKLY",
+		"END:VEVENT",
+	}, "\n")
+
+	// when
+	_, err := crontask.ParseICS(strings.NewReader(ics), nil)
+
+	// then
+	if err == nil {
+		t.Error("expected error for unsupported recurrence rule, got nil")
+	}
+}
+
+func calendarNextTimeTest(t *testing.T) {
+	// given
+	today := time.Now().UTC()
+	calendar := crontask.NewCalendar(
+		nil,
+		crontask.ExcludeWindow(today, today.AddDate(0, 0, 3)),
+	)
+
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		nil,
+		crontask.CronExpression("0 0 * * *"),
+		crontask.BlackoutCalendar(calendar),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while creating task: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	// then
+	if nextTime := task.NextTime(); nextTime.Before(today.AddDate(0, 0, 3)) {
+		t.Errorf("expected next time after exclusion window, got %s", nextTime)
+	}
+}
+
+func mustParse(t *testing.T, expression string) cron.Schedule {
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		t.Fatalf("unexpected error while parsing cron expression: %s", err)
+	}
+
+	return schedule
+}
+
+func assertNextActivations(t *testing.T, schedule cron.Schedule, from time.Time, expected ...time.Time) {
+	t.Helper()
+
+	next := from
+	for _, expectedNext := range expected {
+		next = schedule.Next(next)
+		if !next.Equal(expectedNext) {
+			t.Errorf("expected next activation at %s, got %s", expectedNext, next)
+			return
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package clock provides the source of time of synchronized cron tasks, so
+// schedules, heartbeats and timeouts can be driven by a fake clock in tests -
+// instead of waiting for real time to pass.
+package clock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a source of time, and of timers and tickers running on that time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a new Timer, which sends the time on its
+	// channel after at least the given duration has passed.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a new Ticker, which sends the time on its
+	// channel each time the given period has passed.
+	NewTicker(d time.Duration) Ticker
+
+	// AfterFunc waits for the given duration to pass, and then calls f.
+	// The returned Timer can be used to cancel the call via its Stop method.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the equivalent of a time.Timer, running on a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the equivalent of a time.Ticker, running on a Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real returns the clock of the operating system, as used by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (timer realTimer) C() <-chan time.Time {
+	return timer.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (ticker realTicker) C() <-chan time.Time {
+	return ticker.Ticker.C
+}
+
+// IsReal returns true, if the given clock is the clock of the operating
+// system. A nil clock is considered to be the real clock.
+func IsReal(clock Clock) bool {
+	if clock == nil {
+		return true
+	}
+
+	_, ok := clock.(realClock)
+	return ok
+}
+
+// WithDeadlineCause is the equivalent of context.WithDeadlineCause, with the
+// deadline being reached on the given clock.
+func WithDeadlineCause(parent context.Context, clock Clock, deadline time.Time, cause error) (context.Context, context.CancelFunc) {
+	if IsReal(clock) {
+		return context.WithDeadlineCause(parent, deadline, cause)
+	}
+
+	if cause == nil {
+		cause = context.DeadlineExceeded
+	}
+
+	cancelCtx, cancel := context.WithCancelCause(parent)
+	ctx := &deadlineContext{Context: cancelCtx, deadline: deadline}
+
+	timer := clock.AfterFunc(deadline.Sub(clock.Now()), func() {
+		atomic.StoreInt32(&ctx.exceeded, 1)
+		cancel(cause)
+	})
+
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// WithDeadline is the equivalent of context.WithDeadline, with the
+// deadline being reached on the given clock.
+func WithDeadline(parent context.Context, clock Clock, deadline time.Time) (context.Context, context.CancelFunc) {
+	return WithDeadlineCause(parent, clock, deadline, nil)
+}
+
+// WithTimeout is the equivalent of context.WithTimeout, with the
+// timeout being reached on the given clock.
+func WithTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if clock == nil {
+		clock = Real()
+	}
+
+	return WithDeadline(parent, clock, clock.Now().Add(timeout))
+}
+
+// deadlineContext reports the deadline of a context, which
+// is canceled by a timer of a clock other than the real one.
+type deadlineContext struct {
+	context.Context
+
+	deadline time.Time
+	exceeded int32
+}
+
+func (ctx *deadlineContext) Deadline() (time.Time, bool) {
+	if parent, ok := ctx.Context.Deadline(); ok && parent.Before(ctx.deadline) {
+		return parent, true
+	}
+
+	return ctx.deadline, true
+}
+
+func (ctx *deadlineContext) Err() error {
+	err := ctx.Context.Err()
+	if err != nil && atomic.LoadInt32(&ctx.exceeded) == 1 {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
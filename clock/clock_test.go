@@ -0,0 +1,82 @@
+package clock_test
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests that a deadline on a fake clock is reached by advancing it.
+func Test_WithDeadlineCause_Fake(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+	cause := errors.New("deadline of the test")
+
+	ctx, cancel := clock.WithDeadlineCause(context.Background(), fake, start.Add(time.Minute), cause)
+	defer cancel()
+
+	// when
+	deadline, ok := ctx.Deadline()
+	errBefore := ctx.Err()
+
+	fake.Advance(time.Minute)
+
+	// then
+	if !ok || !deadline.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected deadline %s, got %s", start.Add(time.Minute), deadline)
+	}
+
+	if errBefore != nil {
+		t.Errorf("expected no error before the deadline, got %s", errBefore)
+	}
+
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline to be exceeded, got %v", err)
+	}
+
+	if err := context.Cause(ctx); err != cause {
+		t.Errorf("expected cause %v, got %v", cause, err)
+	}
+}
+
+// Tests that a canceled context on a fake clock stops waiting for its deadline.
+func Test_WithDeadlineCause_Fake_Canceled(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	ctx, cancel := clock.WithDeadline(context.Background(), fake, start.Add(time.Minute))
+
+	// when
+	cancel()
+
+	// then
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("expected context to be canceled, got %v", err)
+	}
+
+	if waiters := fake.Waiters(); waiters != 0 {
+		t.Errorf("expected deadline to stop waiting, got %d waiters", waiters)
+	}
+}
+
+// Tests that a deadline on the real clock is reached in real time.
+func Test_WithTimeout_Real(t *testing.T) {
+	// given
+	ctx, cancel := clock.WithTimeout(context.Background(), clock.Real(), 10*time.Millisecond)
+	defer cancel()
+
+	// when
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected deadline to be reached in real time")
+	}
+
+	// then
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock, whose time only passes when it is advanced. Timers, tickers
+// and functions waiting on the clock are fired while advancing, in the order
+// they are due - so schedules, heartbeats and timeouts can be driven instantly.
+type Fake struct {
+	mutex   sync.Mutex
+	changed chan struct{}
+
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFake creates a new fake clock, whose time starts at the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		changed: make(chan struct{}),
+		now:     now,
+	}
+}
+
+// Now returns the current time of the fake clock.
+func (clock *Fake) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// Advance advances the fake clock by the given duration. All timers and
+// tickers due until then are fired, and functions waiting via AfterFunc are
+// called - before Advance returns.
+func (clock *Fake) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	target := clock.now.Add(d)
+	clock.mutex.Unlock()
+
+	clock.AdvanceTo(target)
+}
+
+// AdvanceTo advances the fake clock to the given time. See Fake.Advance
+// for details. The clock is never set back.
+func (clock *Fake) AdvanceTo(target time.Time) {
+	for {
+		clock.mutex.Lock()
+
+		if len(clock.waiters) == 0 || clock.waiters[0].at.After(target) {
+			if target.After(clock.now) {
+				clock.now = target
+			}
+
+			clock.mutex.Unlock()
+			return
+		}
+
+		waiter := clock.waiters[0]
+		if waiter.at.After(clock.now) {
+			clock.now = waiter.at
+		}
+
+		if waiter.period > 0 {
+			waiter.at = waiter.at.Add(waiter.period)
+			clock.sort()
+		} else {
+			clock.remove(waiter)
+		}
+
+		now := clock.now
+		clock.mutex.Unlock()
+
+		waiter.fire(now)
+	}
+}
+
+// Next returns the time, at which the next timer, ticker or function waiting
+// on the fake clock is due. If nothing is waiting, false is returned.
+func (clock *Fake) Next() (time.Time, bool) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	if len(clock.waiters) == 0 {
+		return time.Time{}, false
+	}
+
+	return clock.waiters[0].at, true
+}
+
+// Waiters returns the number of timers, tickers and functions, which
+// are currently waiting on the fake clock.
+func (clock *Fake) Waiters() int {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return len(clock.waiters)
+}
+
+// BlockUntil blocks until at least the given number of timers, tickers and
+// functions are waiting on the fake clock. This allows to advance the clock
+// only once a goroutine started to wait, e.g. for the next firing of a cron.
+// If the context is done before, its error is returned.
+func (clock *Fake) BlockUntil(ctx context.Context, waiters int) error {
+	for {
+		clock.mutex.Lock()
+		count, changed := len(clock.waiters), clock.changed
+		clock.mutex.Unlock()
+
+		if count >= waiters {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// NewTimer creates a new Timer, which fires once the fake
+// clock was advanced by at least the given duration.
+func (clock *Fake) NewTimer(d time.Duration) Timer {
+	timer := &fakeTimer{fakeWaiter{clock: clock, c: make(chan time.Time, 1)}}
+	timer.schedule(d)
+
+	return timer
+}
+
+// NewTicker creates a new Ticker, which fires each time the
+// fake clock was advanced by the given period.
+func (clock *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake.NewTicker")
+	}
+
+	ticker := &fakeTicker{fakeWaiter{clock: clock, c: make(chan time.Time, 1), period: d}}
+	ticker.schedule(d)
+
+	return ticker
+}
+
+// AfterFunc calls f once the fake clock was advanced by at least the given
+// duration. Durations which are not positive call f in its own goroutine
+// right away.
+func (clock *Fake) AfterFunc(d time.Duration, f func()) Timer {
+	timer := &fakeTimer{fakeWaiter{clock: clock, f: f}}
+	timer.schedule(d)
+
+	return timer
+}
+
+// add adds the given waiter to the fake clock. The mutex must be held.
+func (clock *Fake) add(waiter *fakeWaiter) {
+	clock.waiters = append(clock.waiters, waiter)
+	clock.sort()
+	clock.notify()
+}
+
+// remove removes the given waiter from the fake clock, and returns true if it
+// was waiting at all. The mutex must be held.
+func (clock *Fake) remove(waiter *fakeWaiter) bool {
+	for i, candidate := range clock.waiters {
+		if candidate == waiter {
+			clock.waiters = append(clock.waiters[:i], clock.waiters[i+1:]...)
+			clock.notify()
+			return true
+		}
+	}
+
+	return false
+}
+
+// sort orders the waiters by the time they are due. The mutex must be held.
+func (clock *Fake) sort() {
+	sort.SliceStable(clock.waiters, func(i, j int) bool {
+		return clock.waiters[i].at.Before(clock.waiters[j].at)
+	})
+}
+
+// notify wakes up all goroutines blocked in BlockUntil. The mutex must be held.
+func (clock *Fake) notify() {
+	close(clock.changed)
+	clock.changed = make(chan struct{})
+}
+
+// fakeWaiter is a timer, ticker or function waiting on a fake clock.
+type fakeWaiter struct {
+	clock *Fake
+
+	at     time.Time
+	period time.Duration
+
+	c chan time.Time
+	f func()
+}
+
+// schedule (re)schedules the waiter to fire after the given duration, and
+// returns true if it was waiting before. Durations which are not positive
+// fire right away. For tickers, the duration becomes the new period.
+func (waiter *fakeWaiter) schedule(d time.Duration) bool {
+	waiter.clock.mutex.Lock()
+
+	active := waiter.clock.remove(waiter)
+	waiter.drain()
+
+	now := waiter.clock.now
+	waiter.at = now.Add(d)
+	if waiter.period > 0 {
+		waiter.period = d
+	}
+
+	if d > 0 {
+		waiter.clock.add(waiter)
+		waiter.clock.mutex.Unlock()
+
+		return active
+	}
+
+	waiter.clock.mutex.Unlock()
+
+	if waiter.f != nil {
+		go waiter.f()
+	} else {
+		waiter.fire(now)
+	}
+
+	return active
+}
+
+// stop stops the waiter, and returns true if it was waiting before.
+func (waiter *fakeWaiter) stop() bool {
+	waiter.clock.mutex.Lock()
+	defer waiter.clock.mutex.Unlock()
+
+	active := waiter.clock.remove(waiter)
+	waiter.drain()
+
+	return active
+}
+
+// fire sends the given time on the channel of the waiter, or calls its
+// function. Like with the time package, ticks are dropped for slow receivers.
+func (waiter *fakeWaiter) fire(now time.Time) {
+	if waiter.f != nil {
+		waiter.f()
+		return
+	}
+
+	select {
+	case waiter.c <- now:
+	default:
+	}
+}
+
+// drain discards a stale value from the channel of the waiter, so it is never
+// received after the waiter was stopped or reset - as with the time package.
+func (waiter *fakeWaiter) drain() {
+	if waiter.c == nil {
+		return
+	}
+
+	select {
+	case <-waiter.c:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	fakeWaiter
+}
+
+func (timer *fakeTimer) C() <-chan time.Time {
+	return timer.c
+}
+
+func (timer *fakeTimer) Stop() bool {
+	return timer.stop()
+}
+
+func (timer *fakeTimer) Reset(d time.Duration) bool {
+	return timer.schedule(d)
+}
+
+type fakeTicker struct {
+	fakeWaiter
+}
+
+func (ticker *fakeTicker) C() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *fakeTicker) Stop() {
+	ticker.stop()
+}
+
+func (ticker *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake.Ticker.Reset")
+	}
+
+	ticker.schedule(d)
+}
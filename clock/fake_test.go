@@ -0,0 +1,221 @@
+package clock_test
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Tests that the time of a fake clock only passes when advanced.
+func Test_Fake_Advance(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	// when
+	fake.Advance(time.Minute)
+
+	// then
+	if now := fake.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected fake clock to be advanced to %s, got %s", start.Add(time.Minute), now)
+	}
+}
+
+// Tests that a timer fires once its duration passed, and not before.
+func Test_Fake_Timer(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+	timer := fake.NewTimer(time.Second)
+
+	// when
+	fake.Advance(999 * time.Millisecond)
+
+	// then
+	select {
+	case <-timer.C():
+		t.Fatal("expected timer not to fire before its duration passed")
+	default:
+	}
+
+	// when
+	fake.Advance(time.Millisecond)
+
+	// then
+	select {
+	case fired := <-timer.C():
+		if !fired.Equal(start.Add(time.Second)) {
+			t.Errorf("expected timer to fire at %s, got %s", start.Add(time.Second), fired)
+		}
+	default:
+		t.Fatal("expected timer to fire after its duration passed")
+	}
+}
+
+// Tests that a stopped timer never fires.
+func Test_Fake_Timer_Stop(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+	timer := fake.NewTimer(time.Second)
+
+	// when
+	active := timer.Stop()
+	fake.Advance(time.Minute)
+
+	// then
+	if !active {
+		t.Error("expected timer to be active before it was stopped")
+	}
+
+	select {
+	case <-timer.C():
+		t.Fatal("expected stopped timer not to fire")
+	default:
+	}
+}
+
+// Tests that a reset timer fires after the new duration.
+func Test_Fake_Timer_Reset(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+	timer := fake.NewTimer(time.Second)
+
+	// when
+	timer.Reset(time.Minute)
+	fake.Advance(time.Second)
+
+	// then
+	select {
+	case <-timer.C():
+		t.Fatal("expected reset timer not to fire after its old duration")
+	default:
+	}
+
+	// when
+	fake.Advance(time.Minute)
+
+	// then
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("expected reset timer to fire after its new duration")
+	}
+}
+
+// Tests that a ticker fires every period, and drops ticks for slow receivers.
+func Test_Fake_Ticker(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+	ticker := fake.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// when
+	fake.Advance(time.Second)
+	first := <-ticker.C()
+
+	fake.Advance(3 * time.Second)
+	second := <-ticker.C()
+
+	// then
+	if !first.Equal(start.Add(time.Second)) {
+		t.Errorf("expected first tick at %s, got %s", start.Add(time.Second), first)
+	}
+
+	if !second.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("expected second tick at %s, got %s", start.Add(2*time.Second), second)
+	}
+
+	select {
+	case <-ticker.C():
+		t.Fatal("expected ticks to be dropped for slow receivers")
+	default:
+	}
+}
+
+// Tests that functions are called by Advance, in the order they are due.
+func Test_Fake_AfterFunc(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	var order []int
+	fake.AfterFunc(2*time.Second, func() { order = append(order, 2) })
+	fake.AfterFunc(time.Second, func() { order = append(order, 1) })
+	stopped := fake.AfterFunc(time.Second, func() { order = append(order, 3) })
+
+	// when
+	stopped.Stop()
+	fake.Advance(time.Minute)
+
+	// then
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Errorf("expected functions to be called in order [1 2], got %v", order)
+	}
+}
+
+// Tests that Next returns the time the earliest waiter is due.
+func Test_Fake_Next(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	// when
+	_, waitingBefore := fake.Next()
+
+	fake.NewTimer(time.Minute)
+	fake.NewTimer(time.Second)
+	next, waitingAfter := fake.Next()
+
+	// then
+	if waitingBefore {
+		t.Error("expected nothing to be waiting on a new fake clock")
+	}
+
+	if !waitingAfter || !next.Equal(start.Add(time.Second)) {
+		t.Errorf("expected earliest timer to be due at %s, got %s", start.Add(time.Second), next)
+	}
+}
+
+// Tests that BlockUntil returns, once enough waiters are waiting on the fake clock.
+func Test_Fake_BlockUntil(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fake.NewTimer(time.Second)
+		fake.NewTimer(time.Second)
+	}()
+
+	// when
+	err := fake.BlockUntil(ctx, 2)
+
+	// then
+	if err != nil {
+		t.Errorf("expected to be unblocked by waiting timers, got %s", err)
+	}
+
+	if waiters := fake.Waiters(); waiters != 2 {
+		t.Errorf("expected 2 waiters, got %d", waiters)
+	}
+}
+
+// Tests that BlockUntil gives up, once its context is done.
+func Test_Fake_BlockUntil_Canceled(t *testing.T) {
+	// given
+	fake := clock.NewFake(start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	err := fake.BlockUntil(ctx, 1)
+
+	// then
+	if err != context.Canceled {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
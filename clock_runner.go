@@ -0,0 +1,132 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/robfig/cron/v3"
+
+	"context"
+	"sync"
+	"time"
+)
+
+// runner fires jobs according to their schedules. It is satisfied by
+// *cron.Cron, which always runs on the real clock.
+type runner interface {
+	Schedule(schedule cron.Schedule, job cron.Job) cron.EntryID
+	Start()
+	Stop() context.Context
+}
+
+// newRunner creates a runner on the given clock. The real clock is
+// served by robfig/cron, any other clock by a clockRunner.
+func newRunner(runnerClock clock.Clock, options ...cron.Option) runner {
+	if clock.IsReal(runnerClock) {
+		return cron.New(options...)
+	}
+
+	return &clockRunner{clock: runnerClock, stopped: make(chan struct{})}
+}
+
+// clockRunner fires jobs according to their schedules on a clock, such as
+// a fake one. As with robfig/cron, schedules are evaluated in UTC.
+type clockRunner struct {
+	clock clock.Clock
+
+	mutex   sync.Mutex
+	entries []clockRunnerEntry
+	started bool
+	stopped chan struct{}
+	running sync.WaitGroup
+}
+
+type clockRunnerEntry struct {
+	schedule cron.Schedule
+	job      cron.Job
+}
+
+func (runner *clockRunner) Schedule(schedule cron.Schedule, job cron.Job) cron.EntryID {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+
+	entry := clockRunnerEntry{schedule: schedule, job: job}
+	runner.entries = append(runner.entries, entry)
+
+	if runner.started {
+		go runner.run(entry)
+	}
+
+	return cron.EntryID(len(runner.entries))
+}
+
+func (runner *clockRunner) Start() {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+
+	if runner.started {
+		return
+	}
+
+	runner.started = true
+	for _, entry := range runner.entries {
+		go runner.run(entry)
+	}
+}
+
+// Stop stops the runner from firing any more jobs. The returned
+// context is done, once all running jobs have completed.
+func (runner *clockRunner) Stop() context.Context {
+	runner.mutex.Lock()
+	select {
+	case <-runner.stopped:
+	default:
+		close(runner.stopped)
+	}
+	runner.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		runner.running.Wait()
+		cancel()
+	}()
+
+	return ctx
+}
+
+// run fires the job of the given entry upon every activation of its schedule, until
+// the runner is stopped. Like with robfig/cron, every firing runs in its own goroutine.
+func (runner *clockRunner) run(entry clockRunnerEntry) {
+	for {
+		now := runner.clock.Now().In(time.UTC)
+
+		next := entry.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+
+		timer := runner.clock.NewTimer(next.Sub(now))
+
+		select {
+		case <-runner.stopped:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		// Jobs must not be started once the runner is stopped, as it waits for them
+		runner.mutex.Lock()
+		select {
+		case <-runner.stopped:
+			runner.mutex.Unlock()
+			return
+		default:
+			runner.running.Add(1)
+		}
+		runner.mutex.Unlock()
+
+		go func() {
+			defer runner.running.Done()
+			entry.job.Run()
+		}()
+	}
+}
@@ -0,0 +1,220 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/bsm/redislock"
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClockSkewInterval is the default interval, in which a synchronized
+	// cron task measures the skew of the local clock against the redis server clock -
+	// if it is measured at all. See crontask.ClockSkewInterval for details.
+	DefaultClockSkewInterval = time.Minute
+
+	// DefaultClockSkewThreshold is the default fraction of the lock timeout,
+	// above which a clock skew is logged as a warning.
+	DefaultClockSkewThreshold = 0.25
+)
+
+var luaTime = redis.NewScript(`return redis.call("time")`)
+
+// ClockSkew describes the offset of the local clock of the running instance,
+// compared to the clock of the redis server.
+type ClockSkew struct {
+	// Offset is the time the redis server clock is ahead of the local
+	// clock. Negative offsets mean that the local clock is ahead.
+	Offset time.Duration `json:"offset"`
+
+	// RoundTrip is the round trip time of the measurement. The
+	// offset is accurate within half of the round trip time.
+	RoundTrip time.Duration `json:"roundTrip"`
+
+	MeasuredAt time.Time `json:"measuredAt"`
+}
+
+// ClockSkewHandlerFunc is called with every measurement of the clock skew of a
+// synchronized cron task, e.g. to export the skew as a metric.
+type ClockSkewHandlerFunc func(name string, skew ClockSkew)
+
+// MeasureClockSkew measures the skew of the local clock against the clock of
+// the redis server, via the redis TIME command.
+func MeasureClockSkew(ctx context.Context, client redislock.RedisClient) (ClockSkew, error) {
+	return measureClockSkewOn(ctx, client, clock.Real())
+}
+
+// measureClockSkewOn measures the skew of the given clock against the clock of the redis server.
+func measureClockSkewOn(ctx context.Context, client redislock.RedisClient, localClock clock.Clock) (ClockSkew, error) {
+	start := localClock.Now()
+	now, err := serverTime(ctx, client)
+	roundTrip := localClock.Now().Sub(start)
+	if err != nil {
+		return ClockSkew{}, err
+	}
+
+	// The server time is assumed to be taken halfway through the round trip
+	localTime := start.Add(roundTrip / 2)
+
+	return ClockSkew{
+		Offset:     now.Sub(localTime),
+		RoundTrip:  roundTrip,
+		MeasuredAt: localTime.UTC(),
+	}, nil
+}
+
+// serverTime returns the current time of the redis server, via the redis TIME command.
+func serverTime(ctx context.Context, client redislock.RedisClient) (time.Time, error) {
+	result, err := luaTime.Run(ctx, client, nil).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	parts, ok := result.([]interface{})
+	if !ok || len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("unexpected reply to TIME: %v", result)
+	}
+
+	var values [2]int64
+	for i, part := range parts {
+		value, err := strconv.ParseInt(fmt.Sprint(part), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unexpected reply to TIME: %w", err)
+		}
+
+		values[i] = value
+	}
+
+	return time.Unix(values[0], values[1]*int64(time.Microsecond)).UTC(), nil
+}
+
+// clockSkewTracker keeps track of the latest clock skew measurement.
+type clockSkewTracker struct {
+	mutex sync.RWMutex
+
+	skew     ClockSkew
+	measured bool
+}
+
+func (tracker *clockSkewTracker) set(skew ClockSkew) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.skew, tracker.measured = skew, true
+}
+
+func (tracker *clockSkewTracker) get() (ClockSkew, bool) {
+	tracker.mutex.RLock()
+	defer tracker.mutex.RUnlock()
+
+	return tracker.skew, tracker.measured
+}
+
+// offset returns the latest measured offset, or zero if none was measured yet.
+func (tracker *clockSkewTracker) offset() time.Duration {
+	skew, _ := tracker.get()
+	return skew.Offset
+}
+
+// correctedSchedule shifts a schedule onto the clock of the redis server, so
+// that all instances fire at the same time - regardless of their local clocks.
+type correctedSchedule struct {
+	mutex sync.Mutex
+
+	schedule cron.Schedule
+	tracker  *clockSkewTracker
+
+	// last is the latest activation returned, on the clock of the redis server
+	last time.Time
+}
+
+func (schedule *correctedSchedule) Next(t time.Time) time.Time {
+	schedule.mutex.Lock()
+	defer schedule.mutex.Unlock()
+
+	offset := schedule.tracker.offset()
+
+	// A shrinking offset must not result in the same activation twice
+	from := t.Add(offset)
+	if from.Before(schedule.last) {
+		from = schedule.last
+	}
+
+	next := schedule.schedule.Next(from)
+	if next.IsZero() {
+		return next
+	}
+
+	schedule.last = next
+	return next.Add(-offset)
+}
+
+// ClockSkew returns the latest measured skew of the local clock against the
+// clock of the redis server. If no measurement succeeded yet, false is returned.
+func (synchronizedCronTask *SynchronizedCronTask) ClockSkew() (ClockSkew, bool) {
+	return synchronizedCronTask.clockSkew.get()
+}
+
+// now returns the current time - corrected onto the clock of the
+// redis server, if clock skew correction is enabled.
+func (synchronizedCronTask *SynchronizedCronTask) now() time.Time {
+	now := synchronizedCronTask.clock.Now().UTC()
+	if synchronizedCronTask.clockSkewCorrection {
+		now = now.Add(synchronizedCronTask.clockSkew.offset())
+	}
+
+	return now
+}
+
+// monitorClockSkew measures the clock skew in the given interval, until the task is stopped.
+func (synchronizedCronTask *SynchronizedCronTask) monitorClockSkew(interval time.Duration, threshold time.Duration, handler ClockSkewHandlerFunc) {
+	ticker := synchronizedCronTask.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		synchronizedCronTask.measureClockSkew(threshold, handler)
+
+		select {
+		case <-synchronizedCronTask.shutdownCtx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// measureClockSkew measures the clock skew once, and warns if it exceeds the given threshold.
+func (synchronizedCronTask *SynchronizedCronTask) measureClockSkew(threshold time.Duration, handler ClockSkewHandlerFunc) {
+	ctx, cancel := context.WithTimeout(synchronizedCronTask.shutdownCtx, synchronizedCronTask.lockTimeout)
+	defer cancel()
+
+	skew, err := measureClockSkewOn(ctx, synchronizedCronTask.client, synchronizedCronTask.clock)
+	if err != nil {
+		if synchronizedCronTask.shutdownCtx.Err() == nil {
+			synchronizedCronTask.logger.Warnf("Failed to measure clock skew for synchronized task %q: %s", synchronizedCronTask.name, err)
+		}
+
+		return
+	}
+
+	synchronizedCronTask.clockSkew.set(skew)
+
+	if handler != nil {
+		handler(synchronizedCronTask.name, skew)
+	}
+
+	if skew.Offset > threshold || skew.Offset < -threshold {
+		synchronizedCronTask.logger.Warnf(
+			"Clock of this instance is off by %s from the redis server clock, which exceeds %s - executions of synchronized task %q might not be exactly once",
+			skew.Offset, threshold, synchronizedCronTask.name,
+		)
+	} else {
+		synchronizedCronTask.logger.Tracef("Clock of this instance is off by %s from the redis server clock", skew.Offset)
+	}
+}
@@ -0,0 +1,255 @@
+package crontask
+
+import (
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var luaRecordTrigger = redis.NewScript(`
+	if redis.call("set", KEYS[1], "1", "nx", "px", ARGV[1]) then
+		return 1
+	end
+	return 0
+`)
+
+// triggerRecordTTL is the time a handled trigger is remembered in redis, so
+// instances receiving it late do not execute it again.
+const triggerRecordTTL = 24 * time.Hour
+
+// errTriggerHandled signals that a trigger was skipped, as it
+// was handled by another instance already.
+var errTriggerHandled = errors.New("trigger already handled")
+
+// TriggerResult describes the outcome of a cluster-wide trigger of a
+// synchronized cron task.
+type TriggerResult struct {
+	// Instance is the id of the instance, which executed the task.
+	Instance string
+
+	Duration time.Duration
+
+	// Error is the error of the execution, if any. Errors caused by
+	// ErrStopped, ErrLeadershipTimeout or ErrLockLost match them via
+	// errors.Is, even though they were reported by another instance.
+	Error error
+}
+
+// triggerMessage is published to all instances subscribed for a task.
+type triggerMessage struct {
+	ID      string    `json:"id"`
+	Run     time.Time `json:"run"`
+	ReplyTo string    `json:"replyTo"`
+}
+
+// triggerReply is published by every instance, which received a trigger message.
+type triggerReply struct {
+	Instance string        `json:"instance"`
+	Executed bool          `json:"executed"`
+	Duration time.Duration `json:"duration"`
+	Error    *string       `json:"error"`
+	Cause    string        `json:"cause,omitempty"`
+}
+
+// TriggerCluster triggers the synchronized cron task with the given name on all
+// instances subscribed via the ClusterTrigger option. Just like a firing of the
+// cron, the instances compete for the lock, so the task is executed only once.
+//
+// The call blocks until the task was executed, or all instances reported that
+// they did not execute it. The latter is the case, if the task is currently
+// being executed already. Every trigger is executed at most once, even if an
+// instance receives it after the execution finished. The run is scheduled at the current time of the
+// redis server, so the clock of the triggering process does not matter.
+//
+// The instances to wait for are counted by PUBLISH. On a redis cluster, this only
+// counts the subscribers of the node the message was published on. Cluster clients
+// publish on - and subscribe at - the master of the slot of the channel, so all
+// instances are counted, if every instance subscribes via a cluster client. Instances
+// subscribed via a client connected to another node are not waited for.
+//
+// Tasks within a custom keyspace must be triggered via Keyspace.TriggerCluster.
+func TriggerCluster(ctx context.Context, client redis.UniversalClient, name string) (TriggerResult, error) {
+	return Keyspace{}.TriggerCluster(ctx, client, name)
+}
+
+// TriggerCluster triggers the synchronized cron task with the given name
+// within the keyspace. See crontask.TriggerCluster for details.
+func (keyspace Keyspace) TriggerCluster(ctx context.Context, client redis.UniversalClient, name string) (TriggerResult, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+
+	run, err := serverTime(ctx, client)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+
+	message := triggerMessage{
+		ID:      id,
+		Run:     run,
+		ReplyTo: keyspace.key(name, fmt.Sprintf("trigger.%s", id)),
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return TriggerResult{}, err
+	}
+
+	pubSub := client.Subscribe(ctx, message.ReplyTo)
+	defer pubSub.Close()
+
+	// Ensure the subscription is active, before any reply might be published
+	if _, err := pubSub.Receive(ctx); err != nil {
+		return TriggerResult{}, err
+	}
+
+	receivers, err := client.Publish(ctx, keyspace.key(name, "trigger"), data).Result()
+	if err != nil {
+		return TriggerResult{}, err
+	}
+
+	if receivers == 0 {
+		return TriggerResult{}, fmt.Errorf("no instance is subscribed to triggers of synchronized task %q", name)
+	}
+
+	replies := pubSub.Channel()
+	for i := int64(0); i < receivers; i++ {
+		select {
+		case <-ctx.Done():
+			return TriggerResult{}, ctx.Err()
+		case msg, ok := <-replies:
+			if !ok {
+				return TriggerResult{}, errors.New("subscription closed while waiting for replies")
+			}
+
+			reply := triggerReply{}
+			if err := json.Unmarshal([]byte(msg.Payload), &reply); err != nil {
+				return TriggerResult{}, err
+			}
+
+			if !reply.Executed {
+				continue
+			}
+
+			result := TriggerResult{
+				Instance: reply.Instance,
+				Duration: reply.Duration,
+			}
+
+			if reply.Error != nil {
+				result.Error = remoteError{message: *reply.Error, cause: causeWithMessage(reply.Cause)}
+			}
+
+			return result, nil
+		}
+	}
+
+	return TriggerResult{}, fmt.Errorf("synchronized task %q was not executed by any instance: %w", name, ErrNotElected)
+}
+
+// subscribeToTriggers subscribes the task to cluster-wide triggers. Every trigger is
+// handled like a firing of the cron, until the task is stopped.
+func (synchronizedCronTask *SynchronizedCronTask) subscribeToTriggers(client redis.UniversalClient) {
+	pubSub := client.Subscribe(synchronizedCronTask.shutdownCtx, synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "trigger"))
+
+	go func() {
+		<-synchronizedCronTask.shutdownCtx.Done()
+
+		if err := pubSub.Close(); err != nil {
+			synchronizedCronTask.logger.Warnf("Failed to unsubscribe from triggers of synchronized task %q: %s", synchronizedCronTask.name, err)
+		}
+	}()
+
+	go func() {
+		for msg := range pubSub.Channel() {
+			message := triggerMessage{}
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				synchronizedCronTask.logger.Warnf("Received malformed trigger for synchronized task %q: %s", synchronizedCronTask.name, err)
+				continue
+			}
+
+			// The task might have been stopped in the meantime
+			if synchronizedCronTask.shutdownCtx.Err() != nil {
+				return
+			}
+
+			go synchronizedCronTask.handleTrigger(client, message)
+		}
+	}()
+}
+
+// handleTrigger executes the task for a single cluster-wide trigger, and reports the outcome.
+func (synchronizedCronTask *SynchronizedCronTask) handleTrigger(client redis.UniversalClient, message triggerMessage) {
+	synchronizedCronTask.logger.Debugf("Received cluster-wide trigger for synchronized task %q", synchronizedCronTask.name)
+
+	start := synchronizedCronTask.clock.Now()
+	err := synchronizedCronTask.fire(message.Run, message.ID)
+
+	reply := triggerReply{
+		Instance: synchronizedCronTask.instanceID(),
+		Executed: elected(err),
+		Duration: synchronizedCronTask.clock.Now().Sub(start),
+	}
+
+	if err != nil {
+		errorString := err.Error()
+		reply.Error = &errorString
+
+		if cause := causeOf(err); cause != nil {
+			reply.Cause = cause.Error()
+		}
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		synchronizedCronTask.logger.Errorf("Failed to marshal trigger reply of synchronized task %q: %s", synchronizedCronTask.name, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), synchronizedCronTask.lockTimeout)
+	defer cancel()
+
+	if err := client.Publish(ctx, message.ReplyTo, data).Err(); err != nil {
+		synchronizedCronTask.logger.Warnf("Failed to reply to trigger of synchronized task %q: %s", synchronizedCronTask.name, err)
+	}
+}
+
+// wrapTriggerFunc wraps a task function, so that the trigger with the given id is
+// recorded in redis while the lock is held. Triggers recorded already are skipped.
+func (synchronizedCronTask *SynchronizedCronTask) wrapTriggerFunc(trigger string, taskFunc TaskFunc) TaskFunc {
+	key := synchronizedCronTask.keyspace.key(synchronizedCronTask.name, fmt.Sprintf("triggered.%s", trigger))
+
+	return func(ctx context.Context, task Task) error {
+		recorded, err := luaRecordTrigger.Run(ctx, synchronizedCronTask.client, []string{key}, triggerRecordTTL.Milliseconds()).Int64()
+		if err != nil {
+			return err
+		}
+
+		if recorded == 0 {
+			return electionError{errTriggerHandled}
+		}
+
+		return taskFunc(ctx, task)
+	}
+}
+
+// instanceID returns the id of the running instance, as known by the registry.
+// If the elector is not part of a registry, the hostname is used instead.
+func (elector *elector) instanceID() string {
+	if elector.registry != nil {
+		return elector.registry.InstanceID()
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return hostname
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
+
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskView is the output of the tasks command for a single task.
+type taskView struct {
+	crontask.TaskState
+
+	Paused    bool     `json:"paused"`
+	Instances []string `json:"instances"`
+}
+
+func tasksCommand(ctx context.Context, cli *cli, args []string) error {
+	instances, err := cli.config.keyspace.ListInstances(ctx, cli.client, cli.config.registryKey)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	// Tasks are run by all instances, which registered them
+	running := map[string][]string{}
+	for _, instance := range instances {
+		for _, task := range instance.Tasks {
+			running[task] = append(running[task], instance.ID)
+		}
+	}
+
+	names := args
+	if len(names) == 0 {
+		for name := range running {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+	}
+
+	views := make([]taskView, len(names))
+	for i, name := range names {
+		state, err := cli.config.keyspace.InspectTask(ctx, cli.client, name)
+		if err != nil {
+			return fmt.Errorf("failed to inspect task %q: %w", name, err)
+		}
+
+		views[i] = taskView{
+			TaskState: state,
+			Paused:    state.Paused(),
+			Instances: append([]string{}, running[name]...),
+		}
+	}
+
+	rows := make([][]string, len(views))
+	for i, view := range views {
+		paused := "-"
+		if view.Paused {
+			paused = formatTime(view.PausedSince)
+		}
+
+		lock := "-"
+		if view.Locked {
+			lock = fmt.Sprintf("held for %s", view.LockTTL.Round(time.Millisecond))
+		}
+
+		rows[i] = []string{view.Name, lock, orDash(view.Holder), paused, strconv.Itoa(len(view.Instances))}
+	}
+
+	return cli.printer.print(views, []string{"TASK", "LOCK", "HOLDER", "PAUSED SINCE", "INSTANCES"}, rows)
+}
+
+func instancesCommand(ctx context.Context, cli *cli, args []string) error {
+	if len(args) > 0 {
+		return cli.usageError("the instances command takes no arguments")
+	}
+
+	instances, err := cli.config.keyspace.ListInstances(ctx, cli.client, cli.config.registryKey)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	rows := make([][]string, len(instances))
+	for i, instance := range instances {
+		rows[i] = []string{
+			instance.ID, instance.Hostname, orDash(instance.Version),
+			formatTime(instance.StartTime), strings.Join(instance.Tasks, ", "),
+		}
+	}
+
+	return cli.printer.print(instances, []string{"ID", "HOSTNAME", "VERSION", "STARTED", "TASKS"}, rows)
+}
+
+func runRows(views []timekeeper.RunView) [][]string {
+	rows := make([][]string, len(views))
+	for i, view := range views {
+		rows[i] = []string{
+			view.Name, formatTime(view.LastExecution), view.LastDuration.Round(time.Millisecond).String(),
+			formatTime(view.NextExecution), orDash(view.Error),
+		}
+	}
+
+	return rows
+}
+
+var runHeader = []string{"TASK", "EXECUTED", "DURATION", "NEXT", "ERROR"}
+
+func runsCommand(ctx context.Context, cli *cli, args []string) error {
+	flags := flag.NewFlagSet("runs", flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	offset := flags.Int64("offset", 0, "number of latest runs to skip")
+	limit := flags.Int64("limit", 20, "maximum number of runs to list")
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if flags.NArg() > 0 || *offset < 0 || *limit <= 0 {
+		return cli.usageError("offset must not be negative, and limit must be positive")
+	}
+
+	timeKeeper, err := cli.timeKeeper()
+	if err != nil {
+		return err
+	}
+
+	total, err := timeKeeper.CountAllRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	results, err := timeKeeper.GetAllRuns(ctx, *offset, *limit)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	views := timekeeper.NewRunViews(results...)
+
+	if cli.printer.format == formatJSON {
+		return cli.printer.print(struct {
+			Total int64                `json:"total"`
+			Runs  []timekeeper.RunView `json:"runs"`
+		}{total, views}, nil, nil)
+	}
+
+	if err := cli.printer.print(views, runHeader, runRows(views)); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cli.printer.out, "\n%d of %d runs\n", len(views), total)
+	return err
+}
+
+func lastRunsCommand(ctx context.Context, cli *cli, args []string) error {
+	timeKeeper, err := cli.timeKeeper()
+	if err != nil {
+		return err
+	}
+
+	var results []timekeeper.ExecutionResult
+	if len(args) == 0 {
+		results, err = timeKeeper.GetLastRunOfAllTasks(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list last runs: %w", err)
+		}
+	}
+
+	for _, name := range args {
+		result, err := timeKeeper.GetLastRunOfTask(ctx, name)
+		if errors.Is(err, redis.Nil) {
+			return fmt.Errorf("no run of task %q is recorded", name)
+		} else if err != nil {
+			return fmt.Errorf("failed to get last run of task %q: %w", name, err)
+		}
+
+		results = append(results, result)
+	}
+
+	views := timekeeper.NewRunViews(results...)
+	return cli.printer.print(views, runHeader, runRows(views))
+}
+
+// triggerView is the output of the trigger command.
+type triggerView struct {
+	Name     string        `json:"name"`
+	Instance string        `json:"instance"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func triggerCommand(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return cli.usageError("the trigger command requires exactly one task name")
+	}
+
+	result, err := cli.config.keyspace.TriggerCluster(ctx, cli.client, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to trigger task %q: %w", args[0], err)
+	}
+
+	view := triggerView{
+		Name:     args[0],
+		Instance: result.Instance,
+		Duration: result.Duration,
+	}
+
+	if result.Error != nil {
+		view.Error = result.Error.Error()
+	}
+
+	if err := cli.printer.print(view, []string{"TASK", "INSTANCE", "DURATION", "ERROR"}, [][]string{{
+		view.Name, view.Instance, view.Duration.Round(time.Millisecond).String(), orDash(view.Error),
+	}}); err != nil {
+		return err
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("execution of task %q failed: %w", args[0], result.Error)
+	}
+
+	return nil
+}
+
+// actionView is the output of commands, which modify a single task.
+type actionView struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Result string `json:"result"`
+}
+
+func (cli *cli) printAction(view actionView) error {
+	return cli.printer.print(view, []string{"TASK", "ACTION", "RESULT"}, [][]string{{view.Name, view.Action, view.Result}})
+}
+
+func pauseCommand(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return cli.usageError("the pause command requires exactly one task name")
+	}
+
+	if err := cli.config.keyspace.Pause(ctx, cli.client, args[0]); err != nil {
+		return fmt.Errorf("failed to pause task %q: %w", args[0], err)
+	}
+
+	return cli.printAction(actionView{Name: args[0], Action: "pause", Result: "paused"})
+}
+
+func resumeCommand(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return cli.usageError("the resume command requires exactly one task name")
+	}
+
+	if err := cli.config.keyspace.Resume(ctx, cli.client, args[0]); err != nil {
+		return fmt.Errorf("failed to resume task %q: %w", args[0], err)
+	}
+
+	return cli.printAction(actionView{Name: args[0], Action: "resume", Result: "resumed"})
+}
+
+func releaseCommand(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return cli.usageError("the release command requires exactly one task name")
+	}
+
+	released, err := cli.config.keyspace.ForceRelease(ctx, cli.client, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to release lock of task %q: %w", args[0], err)
+	}
+
+	result := "released"
+	if !released {
+		result = "not locked"
+	}
+
+	return cli.printAction(actionView{Name: args[0], Action: "release", Result: result})
+}
@@ -0,0 +1,203 @@
+// Command crontaskctl inspects and controls synchronized cron tasks, by talking
+// to the same redis as the services running them.
+//
+// Usage:
+//
+//	crontaskctl [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	tasks [name...]            list tasks with their lock holders, lock TTLs and pause state
+//	instances                  list all live instances of the registry
+//	runs [-offset n] [-limit n] list recorded runs of the time keeper, latest first
+//	last-runs [name...]        list the last recorded run of all (or the given) tasks
+//	trigger <name>             trigger a task cluster-wide, and wait for its execution
+//	pause <name>               pause a task on all instances
+//	resume <name>              resume a paused task
+//	release <name>             force-release a stuck lock of a task
+//
+// Without arguments, the tasks command lists all tasks of the live instances of
+// the registry. Thus, only tasks added to a registry via the InstanceRegistry
+// option are listed - other tasks can be inspected by their name.
+package main
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
+
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// errUsage signals that the command line is malformed. The usage
+// has already been printed, when this error is returned.
+var errUsage = errors.New("invalid usage")
+
+// config bundles the global flags.
+type config struct {
+	addrs    string
+	username string
+	password string
+	db       int
+
+	keyspace    crontask.Keyspace
+	registryKey string
+
+	execListName string
+	lastExecName string
+
+	output  string
+	timeout time.Duration
+}
+
+// command is a single sub command of the cli.
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, cli *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"tasks":     {"tasks [name...]", "list tasks with their lock holders, lock TTLs and pause state", tasksCommand},
+	"instances": {"instances", "list all live instances of the registry", instancesCommand},
+	"runs":      {"runs [-offset n] [-limit n]", "list recorded runs of the time keeper, latest first", runsCommand},
+	"last-runs": {"last-runs [name...]", "list the last recorded run of all (or the given) tasks", lastRunsCommand},
+	"trigger":   {"trigger <name>", "trigger a task cluster-wide, and wait for its execution", triggerCommand},
+	"pause":     {"pause <name>", "pause a task on all instances", pauseCommand},
+	"resume":    {"resume <name>", "resume a paused task", resumeCommand},
+	"release":   {"release <name>", "force-release a stuck lock of a task", releaseCommand},
+}
+
+var commandOrder = []string{"tasks", "instances", "runs", "last-runs", "trigger", "pause", "resume", "release"}
+
+// cli bundles everything required by the sub commands.
+type cli struct {
+	config config
+	usage  string
+
+	client  redis.UniversalClient
+	printer *printer
+	stderr  io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the cli with the given arguments, and returns its exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	cfg, command, commandArgs, err := parseArgs(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+
+		return 2
+	}
+
+	if cfg.output != formatTable && cfg.output != formatJSON {
+		fmt.Fprintf(stderr, "unknown output format %q - must be %q or %q\n", cfg.output, formatTable, formatJSON)
+		return 2
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    strings.Split(cfg.addrs, ","),
+		Username: cfg.username,
+		Password: cfg.password,
+		DB:       cfg.db,
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	err = command.run(ctx, &cli{
+		config:  cfg,
+		usage:   command.usage,
+		client:  client,
+		printer: &printer{format: cfg.output, out: stdout},
+		stderr:  stderr,
+	}, commandArgs)
+
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "crontaskctl: %s\n", err)
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseArgs parses the global flags, and determines the sub command.
+func parseArgs(args []string, stderr io.Writer) (config, command, []string, error) {
+	cfg := config{}
+
+	flags := flag.NewFlagSet("crontaskctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: crontaskctl [flags] <command> [arguments]\n\nCommands:\n")
+		for _, name := range commandOrder {
+			fmt.Fprintf(stderr, "  %-28s %s\n", commands[name].usage, commands[name].description)
+		}
+
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&cfg.addrs, "addr", "127.0.0.1:6379", "comma separated redis addresses - several addresses denote a redis cluster")
+	flags.StringVar(&cfg.username, "username", "", "redis username")
+	flags.StringVar(&cfg.password, "password", os.Getenv("REDIS_PASSWORD"), "redis password, defaults to $REDIS_PASSWORD")
+	flags.IntVar(&cfg.db, "db", 0, "redis database")
+	flags.StringVar(&cfg.keyspace.Namespace, "namespace", "", "namespace of the keyspace of the tasks")
+	flags.BoolVar(&cfg.keyspace.HashTags, "hash-tags", false, "whether the keyspace of the tasks uses redis cluster hash tags")
+	flags.StringVar(&cfg.registryKey, "registry-key", crontask.DefaultRegistryKey, "redis key of the instance registry")
+	flags.StringVar(&cfg.execListName, "exec-list", timekeeper.DefaultRedisExecListName, "redis key of the execution list of the time keeper")
+	flags.StringVar(&cfg.lastExecName, "last-exec", timekeeper.DefaultRedisLastExecName, "redis key of the last executions of the time keeper")
+	flags.StringVar(&cfg.output, "output", formatTable, `output format, "table" or "json"`)
+	flags.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "timeout of the command - triggers wait for the execution of the task")
+
+	if err := flags.Parse(args); err != nil {
+		return config{}, command{}, nil, err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return config{}, command{}, nil, errUsage
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		return config{}, command{}, nil, errUsage
+	}
+
+	return cfg, cmd, flags.Args()[1:], nil
+}
+
+// usageError prints the usage of the running command, and returns errUsage.
+func (cli *cli) usageError(reason string) error {
+	fmt.Fprintf(cli.stderr, "%s\nUsage: crontaskctl [flags] %s\n", reason, cli.usage)
+	return errUsage
+}
+
+// timeKeeper creates a read-only time keeper, which does not clean up old runs.
+func (cli *cli) timeKeeper() (*timekeeper.TimeKeeper, error) {
+	return timekeeper.NewTimeKeeperWithOptions(cli.client, &timekeeper.Options{
+		RedisExecListName: cli.config.execListName,
+		RedisLastExecName: cli.config.lastExecName,
+
+		KeepTaskList: true,
+		KeepLastTask: true,
+	})
+}
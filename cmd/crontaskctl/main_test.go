@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_Run(t *testing.T) {
+	t.Run("usage", usageTest)
+
+	t.Run("unknown-command", unknownCommandTest)
+
+	t.Run("unknown-output-format", unknownOutputFormatTest)
+
+	t.Run("missing-task-name", missingTaskNameTest)
+}
+
+func usageTest(t *testing.T) {
+	// given
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// when
+	code := run(nil, stdout, stderr)
+
+	// then
+	if code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+
+	for _, name := range commandOrder {
+		if !strings.Contains(stderr.String(), commands[name].usage) {
+			t.Errorf("expected usage to contain command %q, got %q", name, stderr.String())
+		}
+	}
+}
+
+func unknownCommandTest(t *testing.T) {
+	// given
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// when
+	code := run([]string{"explode"}, stdout, stderr)
+
+	// then
+	if code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+
+	if !strings.Contains(stderr.String(), `unknown command "explode"`) {
+		t.Errorf("expected unknown command to be reported, got %q", stderr.String())
+	}
+}
+
+func unknownOutputFormatTest(t *testing.T) {
+	// given
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// when
+	code := run([]string{"-output", "xml", "tasks"}, stdout, stderr)
+
+	// then
+	if code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+
+	if !strings.Contains(stderr.String(), `unknown output format "xml"`) {
+		t.Errorf("expected unknown output format to be reported, got %q", stderr.String())
+	}
+}
+
+func missingTaskNameTest(t *testing.T) {
+	for _, name := range []string{"trigger", "pause", "resume", "release"} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			// given
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			// when
+			code := run([]string{"-addr", "127.0.0.1:0", name}, stdout, stderr)
+
+			// then
+			if code != 2 {
+				t.Errorf("expected exit code 2, got %d", code)
+			}
+
+			if !strings.Contains(stderr.String(), "Usage: crontaskctl [flags] "+commands[name].usage) {
+				t.Errorf("expected usage of command to be printed, got %q", stderr.String())
+			}
+
+			if stdout.Len() > 0 {
+				t.Errorf("expected no output, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func Test_Printer(t *testing.T) {
+	t.Run("table", tablePrinterTest)
+
+	t.Run("json", jsonPrinterTest)
+}
+
+func tablePrinterTest(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	printer := &printer{format: formatTable, out: out}
+
+	// when
+	err := printer.print(nil, []string{"TASK", "RESULT"}, [][]string{{"some-task", "paused"}, {"other", "-"}})
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "TASK       RESULT\nsome-task  paused\nother      -\n"
+	if out.String() != expected {
+		t.Errorf("expected table %q, got %q", expected, out.String())
+	}
+}
+
+func jsonPrinterTest(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	printer := &printer{format: formatJSON, out: out}
+
+	// when
+	err := printer.print(actionView{Name: "some-task", Action: "pause", Result: "paused"}, []string{"ignored"}, nil)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	view := actionView{}
+	if err := json.Unmarshal(out.Bytes(), &view); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if view.Name != "some-task" || view.Action != "pause" || view.Result != "paused" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// printer prints the output of commands, either as an aligned table or as JSON.
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+// print prints the value as JSON, or the header and rows as a table.
+func (printer *printer) print(value interface{}, header []string, rows [][]string) error {
+	if printer.format == formatJSON {
+		encoder := json.NewEncoder(printer.out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(value)
+	}
+
+	writer := tabwriter.NewWriter(printer.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,347 @@
+package crontask
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFormat is the format of a task configuration document.
+type ConfigFormat int
+
+const (
+	// ConfigYAML denotes a YAML document.
+	ConfigYAML ConfigFormat = iota
+	// ConfigJSON denotes a JSON document.
+	ConfigJSON
+)
+
+// Config describes synchronized cron tasks by their name.
+type Config struct {
+	Tasks map[string]TaskConfig `json:"tasks" yaml:"tasks"`
+}
+
+// TaskConfig describes a single synchronized cron task. Unset fields
+// keep the values of the options the task is bound with. Durations
+// are given in the format of time.ParseDuration, e.g. "1m30s".
+type TaskConfig struct {
+	CronExpression  string   `json:"cronExpression,omitempty" yaml:"cronExpression,omitempty"`
+	CronExpressions []string `json:"cronExpressions,omitempty" yaml:"cronExpressions,omitempty"`
+
+	// Zone is the name of the location the cron expressions are evaluated
+	// in, e.g. "Europe/Berlin". Expressions with a CRON_TZ prefix keep theirs.
+	Zone string `json:"zone,omitempty" yaml:"zone,omitempty"`
+
+	LeadershipTimeout string `json:"leadershipTimeout,omitempty" yaml:"leadershipTimeout,omitempty"`
+	SoftTimeout       string `json:"softTimeout,omitempty" yaml:"softTimeout,omitempty"`
+	LockTimeout       string `json:"lockTimeout,omitempty" yaml:"lockTimeout,omitempty"`
+	LockHeartbeat     string `json:"lockHeartbeat,omitempty" yaml:"lockHeartbeat,omitempty"`
+
+	// Enabled defaults to true, if unset.
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+}
+
+// BoundTask is a configured task, bound to its task function.
+type BoundTask struct {
+	TaskFunc TaskFunc
+	Options  *TaskOptions
+}
+
+// ConfigLoader binds task configuration documents to task functions,
+// which are registered by the name of their task.
+type ConfigLoader struct {
+	envPrefix string
+	setters   []TaskOption
+
+	taskFuncs map[string]TaskFunc
+}
+
+// NewConfigLoader creates a new ConfigLoader. Unless the envPrefix is empty, the
+// loaded configuration is overridden by environment variables, named after the
+// prefix, the task and the field, e.g. CRONTASK_NIGHTLY_IMPORT_LOCK_TIMEOUT for
+// the prefix "CRONTASK" and the task "nightly-import". Supported fields are
+// CRON_EXPRESSION (which replaces all cron expressions), ZONE, LEADERSHIP_TIMEOUT,
+// SOFT_TIMEOUT, LOCK_TIMEOUT, LOCK_HEARTBEAT and ENABLED.
+//
+// The given setters are applied to the options of all tasks, before the
+// configuration is applied.
+func NewConfigLoader(envPrefix string, setters ...TaskOption) *ConfigLoader {
+	return &ConfigLoader{
+		envPrefix: envPrefix,
+		setters:   setters,
+		taskFuncs: map[string]TaskFunc{},
+	}
+}
+
+// Register registers the task function of the task with the given name.
+func (loader *ConfigLoader) Register(name string, taskFunc TaskFunc) *ConfigLoader {
+	loader.taskFuncs[name] = taskFunc
+
+	return loader
+}
+
+// LoadFile loads the configuration document at the given path. The format is
+// derived from the file extension - ".json" for JSON, and YAML otherwise.
+func (loader *ConfigLoader) LoadFile(path string) ([]BoundTask, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := ConfigYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = ConfigJSON
+	}
+
+	return loader.Load(file, format)
+}
+
+// Load loads the given configuration document, and binds it to the registered
+// task functions. The returned tasks are sorted by name, and exclude disabled
+// tasks. Loading fails on unknown fields, tasks which are not registered,
+// registered tasks which are not configured, and invalid values - including
+// options, which do not pass TaskOptions.Validate.
+func (loader *ConfigLoader) Load(reader io.Reader, format ConfigFormat) ([]BoundTask, error) {
+	config, err := decodeConfig(reader, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode task configuration: %w", err)
+	}
+
+	return loader.Bind(config)
+}
+
+// Bind binds the given configuration to the registered task functions, just like Load.
+// All tasks which are registered but not configured - or vice versa - are reported at once.
+func (loader *ConfigLoader) Bind(config Config) ([]BoundTask, error) {
+	var unconfigured []string
+	for name := range loader.taskFuncs {
+		if _, ok := config.Tasks[name]; !ok {
+			unconfigured = append(unconfigured, name)
+		}
+	}
+
+	if len(unconfigured) > 0 {
+		sort.Strings(unconfigured)
+		return nil, fmt.Errorf("registered tasks %s are not configured - disable them via enabled: false instead", quoteNames(unconfigured))
+	}
+
+	var unregistered []string
+	names := make([]string, 0, len(config.Tasks))
+	for name := range config.Tasks {
+		if _, ok := loader.taskFuncs[name]; !ok {
+			unregistered = append(unregistered, name)
+		}
+
+		names = append(names, name)
+	}
+
+	if len(unregistered) > 0 {
+		sort.Strings(unregistered)
+		return nil, fmt.Errorf("configured tasks %s are not registered", quoteNames(unregistered))
+	}
+
+	sort.Strings(names)
+
+	tasks := make([]BoundTask, 0, len(names))
+	for _, name := range names {
+		taskConfig := config.Tasks[name]
+		if err := loader.overrideFromEnv(name, &taskConfig); err != nil {
+			return nil, fmt.Errorf("task %q: %w", name, err)
+		}
+
+		if taskConfig.Enabled != nil && !*taskConfig.Enabled {
+			continue
+		}
+
+		options, err := loader.taskOptions(name, taskConfig)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", name, err)
+		}
+
+		tasks = append(tasks, BoundTask{
+			TaskFunc: loader.taskFuncs[name],
+			Options:  options,
+		})
+	}
+
+	return tasks, nil
+}
+
+// quoteNames quotes and joins the given task names, e.g. for error messages.
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+func decodeConfig(reader io.Reader, format ConfigFormat) (Config, error) {
+	var config Config
+
+	switch format {
+	case ConfigYAML:
+		decoder := yaml.NewDecoder(reader)
+		decoder.KnownFields(true)
+
+		// An empty document is an empty configuration
+		if err := decoder.Decode(&config); err != nil && err != io.EOF {
+			return Config{}, err
+		}
+	case ConfigJSON:
+		decoder := json.NewDecoder(reader)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&config); err != nil {
+			return Config{}, err
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown format %d", format)
+	}
+
+	return config, nil
+}
+
+// taskOptions applies the task configuration on top of the default options.
+func (loader *ConfigLoader) taskOptions(name string, taskConfig TaskConfig) (*TaskOptions, error) {
+	options := defaultTaskOptions()
+	for _, setter := range loader.setters {
+		setter(options)
+	}
+
+	options.Name = name
+
+	if taskConfig.CronExpression != "" {
+		options.CronExpression = taskConfig.CronExpression
+	}
+
+	if len(taskConfig.CronExpressions) > 0 {
+		options.CronExpressions = taskConfig.CronExpressions
+	}
+
+	if taskConfig.Zone != "" {
+		if _, err := time.LoadLocation(taskConfig.Zone); err != nil {
+			return nil, fmt.Errorf("invalid zone: %w", err)
+		}
+
+		options.CronExpression = inZone(options.CronExpression, taskConfig.Zone)
+		zoned := make([]string, len(options.CronExpressions))
+		for i, cronExpression := range options.CronExpressions {
+			zoned[i] = inZone(cronExpression, taskConfig.Zone)
+		}
+		options.CronExpressions = zoned
+	}
+
+	durations := []struct {
+		field  string
+		value  string
+		target *time.Duration
+	}{
+		{"leadershipTimeout", taskConfig.LeadershipTimeout, &options.LeadershipTimeout},
+		{"softTimeout", taskConfig.SoftTimeout, &options.SoftTimeout},
+		{"lockTimeout", taskConfig.LockTimeout, &options.LockTimeout},
+		{"lockHeartbeat", taskConfig.LockHeartbeat, &options.LockHeartbeat},
+	}
+
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", duration.field, err)
+		}
+
+		*duration.target = parsed
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// overrideFromEnv overrides the task configuration with environment variables.
+func (loader *ConfigLoader) overrideFromEnv(name string, taskConfig *TaskConfig) error {
+	if loader.envPrefix == "" {
+		return nil
+	}
+
+	lookup := func(field string) (string, bool) {
+		return os.LookupEnv(envName(loader.envPrefix, name, field))
+	}
+
+	if value, ok := lookup("CRON_EXPRESSION"); ok {
+		taskConfig.CronExpression = value
+		taskConfig.CronExpressions = nil
+	}
+
+	if value, ok := lookup("ZONE"); ok {
+		taskConfig.Zone = value
+	}
+
+	if value, ok := lookup("LEADERSHIP_TIMEOUT"); ok {
+		taskConfig.LeadershipTimeout = value
+	}
+
+	if value, ok := lookup("SOFT_TIMEOUT"); ok {
+		taskConfig.SoftTimeout = value
+	}
+
+	if value, ok := lookup("LOCK_TIMEOUT"); ok {
+		taskConfig.LockTimeout = value
+	}
+
+	if value, ok := lookup("LOCK_HEARTBEAT"); ok {
+		taskConfig.LockHeartbeat = value
+	}
+
+	if value, ok := lookup("ENABLED"); ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", envName(loader.envPrefix, name, "ENABLED"), err)
+		}
+
+		taskConfig.Enabled = &enabled
+	}
+
+	return nil
+}
+
+// envName returns the name of the environment variable overriding the given field
+// of a task. All characters of the task name, which are neither letters nor
+// digits, are replaced by underscores.
+func envName(prefix string, name string, field string) string {
+	var normalized bytes.Buffer
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			normalized.WriteRune(r)
+		} else {
+			normalized.WriteByte('_')
+		}
+	}
+
+	return prefix + "_" + normalized.String() + "_" + field
+}
+
+// inZone prefixes the cron expression with the given zone, unless it specifies its own.
+func inZone(cronExpression string, zone string) string {
+	if strings.HasPrefix(cronExpression, "TZ=") || strings.HasPrefix(cronExpression, "CRON_TZ=") {
+		return cronExpression
+	}
+
+	return "CRON_TZ=" + zone + " " + cronExpression
+}
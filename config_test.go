@@ -0,0 +1,300 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ConfigLoader(t *testing.T) {
+	t.Run("yaml", yamlConfigTest)
+
+	t.Run("json-file", jsonConfigFileTest)
+
+	t.Run("env-overrides", envOverridesConfigTest)
+
+	t.Run("disabled", disabledConfigTest)
+
+	t.Run("unknown-task", unknownTaskConfigTest)
+
+	t.Run("unconfigured-task", unconfiguredTaskConfigTest)
+
+	t.Run("unknown-field", unknownFieldConfigTest)
+
+	t.Run("invalid-values", invalidValuesConfigTest)
+}
+
+func noopTaskFunc(context.Context, crontask.Task) error {
+	return nil
+}
+
+func yamlConfigTest(t *testing.T) {
+	// given
+	loader := crontask.NewConfigLoader("", crontask.LockTimeout(10*time.Second)).
+		Register("nightly-import", noopTaskFunc).
+		Register("cleanup", noopTaskFunc)
+
+	document := `
+tasks:
+  nightly-import:
+    cronExpression: "0 0 2 * * *"
+    zone: Europe/Berlin
+    leadershipTimeout: 1h
+    softTimeout: 5m
+    lockHeartbeat: 2s
+  cleanup:
+    cronExpressions: ["0 */15 * * * *", "CRON_TZ=UTC 0 0 12 * * *"]
+    zone: Europe/Berlin
+`
+
+	// when
+	tasks, err := loader.Load(strings.NewReader(document), crontask.ConfigYAML)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+
+	cleanup, nightlyImport := tasks[0].Options, tasks[1].Options
+
+	if nightlyImport.Name != "nightly-import" {
+		t.Errorf("expected name %q, got %q", "nightly-import", nightlyImport.Name)
+	}
+
+	if expected := "CRON_TZ=Europe/Berlin 0 0 2 * * *"; nightlyImport.CronExpression != expected {
+		t.Errorf("expected cron expression %q, got %q", expected, nightlyImport.CronExpression)
+	}
+
+	if nightlyImport.LeadershipTimeout != time.Hour {
+		t.Errorf("expected leadership timeout of %s, got %s", time.Hour, nightlyImport.LeadershipTimeout)
+	}
+
+	if nightlyImport.SoftTimeout != 5*time.Minute {
+		t.Errorf("expected soft timeout of %s, got %s", 5*time.Minute, nightlyImport.SoftTimeout)
+	}
+
+	// Set by the options of the loader
+	if nightlyImport.LockTimeout != 10*time.Second {
+		t.Errorf("expected lock timeout of %s, got %s", 10*time.Second, nightlyImport.LockTimeout)
+	}
+
+	if nightlyImport.LockHeartbeat != 2*time.Second {
+		t.Errorf("expected lock heartbeat of %s, got %s", 2*time.Second, nightlyImport.LockHeartbeat)
+	}
+
+	expected := []string{"CRON_TZ=Europe/Berlin 0 */15 * * * *", "CRON_TZ=UTC 0 0 12 * * *"}
+	if strings.Join(cleanup.CronExpressions, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected cron expressions %q, got %q", expected, cleanup.CronExpressions)
+	}
+
+	if cleanup.LockHeartbeat != crontask.DefaultLockHeartbeat {
+		t.Errorf("expected default lock heartbeat of %s, got %s", crontask.DefaultLockHeartbeat, cleanup.LockHeartbeat)
+	}
+}
+
+func jsonConfigFileTest(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	document := `{"tasks": {"cleanup": {"cronExpression": "@hourly", "lockTimeout": "20s"}}}`
+	if err := os.WriteFile(path, []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := crontask.NewConfigLoader("").Register("cleanup", noopTaskFunc)
+
+	// when
+	tasks, err := loader.LoadFile(path)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 task, got %d", len(tasks))
+	}
+
+	if tasks[0].Options.CronExpression != "@hourly" {
+		t.Errorf("expected cron expression %q, got %q", "@hourly", tasks[0].Options.CronExpression)
+	}
+
+	if tasks[0].Options.LockTimeout != 20*time.Second {
+		t.Errorf("expected lock timeout of %s, got %s", 20*time.Second, tasks[0].Options.LockTimeout)
+	}
+}
+
+func envOverridesConfigTest(t *testing.T) {
+	// given
+	t.Setenv("CRONTASK_NIGHTLY_IMPORT_CRON_EXPRESSION", "0 30 3 * * *")
+	t.Setenv("CRONTASK_NIGHTLY_IMPORT_LOCK_TIMEOUT", "15s")
+
+	loader := crontask.NewConfigLoader("CRONTASK").Register("nightly-import", noopTaskFunc)
+
+	document := `
+tasks:
+  nightly-import:
+    cronExpressions: ["0 0 2 * * *"]
+    lockTimeout: 5s
+`
+
+	// when
+	tasks, err := loader.Load(strings.NewReader(document), crontask.ConfigYAML)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	options := tasks[0].Options
+	if options.CronExpression != "0 30 3 * * *" || len(options.CronExpressions) != 0 {
+		t.Errorf("expected cron expression to be overridden, got %q and %q", options.CronExpression, options.CronExpressions)
+	}
+
+	if options.LockTimeout != 15*time.Second {
+		t.Errorf("expected lock timeout of %s, got %s", 15*time.Second, options.LockTimeout)
+	}
+}
+
+func disabledConfigTest(t *testing.T) {
+	// given
+	t.Setenv("CRONTASK_CLEANUP_ENABLED", "false")
+
+	loader := crontask.NewConfigLoader("CRONTASK").
+		Register("cleanup", noopTaskFunc).
+		Register("nightly-import", noopTaskFunc)
+
+	document := `
+tasks:
+  cleanup: {}
+  nightly-import:
+    enabled: false
+`
+
+	// when
+	tasks, err := loader.Load(strings.NewReader(document), crontask.ConfigYAML)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(tasks) != 0 {
+		t.Errorf("expected no tasks, got %d", len(tasks))
+	}
+}
+
+func unknownTaskConfigTest(t *testing.T) {
+	// given
+	loader := crontask.NewConfigLoader("").Register("cleanup", noopTaskFunc)
+
+	document := `{"tasks": {"cleanup": {}, "clenaup": {}, "archvie": {}}}`
+
+	// when
+	_, err := loader.Load(strings.NewReader(document), crontask.ConfigJSON)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), `"archvie", "clenaup" are not registered`) {
+		t.Errorf("expected error for all unknown tasks, got %v", err)
+	}
+}
+
+func unconfiguredTaskConfigTest(t *testing.T) {
+	// given
+	loader := crontask.NewConfigLoader("").
+		Register("import", noopTaskFunc).
+		Register("cleanup", noopTaskFunc).
+		Register("archive", noopTaskFunc).
+		Register("report", noopTaskFunc)
+
+	document := `{"tasks": {"report": {}}}`
+
+	// when
+	_, err := loader.Load(strings.NewReader(document), crontask.ConfigJSON)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), `"archive", "cleanup", "import" are not configured`) {
+		t.Errorf("expected error for all unconfigured tasks, got %v", err)
+	}
+}
+
+func unknownFieldConfigTest(t *testing.T) {
+	// given
+	loader := crontask.NewConfigLoader("").Register("cleanup", noopTaskFunc)
+
+	document := `
+tasks:
+  cleanup:
+    lockTimeuot: 5s
+`
+
+	// when
+	_, err := loader.Load(strings.NewReader(document), crontask.ConfigYAML)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "lockTimeuot") {
+		t.Errorf("expected error for unknown field, got %v", err)
+	}
+}
+
+func invalidValuesConfigTest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		env      map[string]string
+	}{
+		{name: "duration", document: `{"tasks": {"cleanup": {"lockTimeout": "5"}}}`},
+		{name: "zone", document: `{"tasks": {"cleanup": {"zone": "Mars/Olympus_Mons"}}}`},
+		{name: "cron-expression", document: `{"tasks": {"cleanup": {"cronExpression": "aint-work"}}}`},
+		{name: "heartbeat", document: `{"tasks": {"cleanup": {"lockTimeout": "1s", "lockHeartbeat": "2s"}}}`},
+		{name: "env", document: `{"tasks": {"cleanup": {}}}`, env: map[string]string{"CRONTASK_CLEANUP_ENABLED": "maybe"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			for key, value := range testCase.env {
+				t.Setenv(key, value)
+			}
+
+			loader := crontask.NewConfigLoader("CRONTASK").Register("cleanup", noopTaskFunc)
+
+			// when
+			_, err := loader.Load(strings.NewReader(testCase.document), crontask.ConfigJSON)
+
+			// then
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			if !strings.Contains(err.Error(), `task "cleanup"`) {
+				t.Errorf("expected error to name the task, got %q", err)
+			}
+		})
+	}
+
+	t.Run("validation-error", func(t *testing.T) {
+		// given
+		loader := crontask.NewConfigLoader("").Register("cleanup", noopTaskFunc)
+
+		// when
+		_, err := loader.Load(strings.NewReader(`{"tasks": {"cleanup": {"lockTimeout": "-1s"}}}`), crontask.ConfigJSON)
+
+		// then
+		var validationErr *crontask.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+}
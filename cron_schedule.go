@@ -0,0 +1,319 @@
+package crontask
+
+import (
+	"github.com/robfig/cron/v3"
+
+	"fmt"
+	"strings"
+	"time"
+)
+
+// starBit is set by robfig/cron for fields, which were given as "*" or "?".
+const starBit = 1 << 63
+
+// cronField describes the bounds and naming of a single field of a cron expression.
+type cronField struct {
+	min, max uint
+	unit     string
+}
+
+var (
+	secondField = cronField{0, 59, "second"}
+	minuteField = cronField{0, 59, "minute"}
+	hourField   = cronField{0, 23, "hour"}
+	domField    = cronField{1, 31, "day"}
+	monthField  = cronField{1, 12, "month"}
+	dowField    = cronField{0, 6, "weekday"}
+)
+
+// CronSchedule is a cron expression, parsed exactly as synchronized cron tasks
+// parse theirs. It can be used to validate and preview cron expressions, and
+// also to schedule a task via the TaskSchedule option.
+type CronSchedule struct {
+	expression string
+	schedule   cron.Schedule
+}
+
+// ParseSchedule parses the given cron expression with the parser used by
+// synchronized cron tasks. That is, the seconds field is optional, and
+// descriptors such as "@daily" or "@every 1h" are supported.
+func ParseSchedule(expression string) (*CronSchedule, error) {
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CronSchedule{
+		expression: expression,
+		schedule:   schedule,
+	}, nil
+}
+
+// Next returns the next activation time, later than the given time.
+func (schedule *CronSchedule) Next(t time.Time) time.Time {
+	return schedule.schedule.Next(t)
+}
+
+// String returns the cron expression of the schedule.
+func (schedule *CronSchedule) String() string {
+	return schedule.expression
+}
+
+// Preview returns up to n upcoming activation times after the given time. The
+// cron expression is evaluated in the given location - unless it specifies its
+// own via a CRON_TZ prefix - which defaults to UTC if nil. Note that synchronized
+// cron tasks evaluate cron expressions in UTC. If n is not positive, an empty
+// slice is returned.
+func (schedule *CronSchedule) Preview(from time.Time, n int, location *time.Location) []time.Time {
+	if n <= 0 {
+		return []time.Time{}
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	activations := make([]time.Time, 0, n)
+
+	next := from.In(location)
+	for i := 0; i < n; i++ {
+		next = schedule.schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+
+		activations = append(activations, next.In(location))
+	}
+
+	return activations
+}
+
+// Describe returns a human-readable English description of the schedule,
+// e.g. "Every 5 minutes, between 09:00 and 17:59, Monday through Friday".
+func (schedule *CronSchedule) Describe() string {
+	switch s := schedule.schedule.(type) {
+	case cron.ConstantDelaySchedule:
+		return fmt.Sprintf("Every %s", s.Delay)
+	case *cron.SpecSchedule:
+		return describeSpec(s, describeDays(s))
+	case *extendedSchedule:
+		return describeSpec(s.base, s.describeDays())
+	default:
+		return schedule.expression
+	}
+}
+
+// describeSpec describes a spec schedule, with the given description of
+// the days it is restricted to - if any.
+func describeSpec(spec *cron.SpecSchedule, days string) string {
+	segments := describeTimeOfDay(
+		fieldValues(spec.Second, secondField),
+		fieldValues(spec.Minute, minuteField),
+		fieldValues(spec.Hour, hourField),
+	)
+
+	if days != "" {
+		segments = append(segments, days)
+	}
+
+	if months := describeMonths(fieldValues(spec.Month, monthField)); months != "" {
+		segments = append(segments, months)
+	}
+
+	if len(segments) == 0 {
+		segments = []string{"every second"}
+	}
+
+	description := strings.Join(segments, ", ")
+	description = strings.ToUpper(description[:1]) + description[1:]
+
+	if spec.Location != nil && spec.Location != time.Local {
+		description += fmt.Sprintf(" (%s)", spec.Location)
+	}
+
+	return description
+}
+
+func describeTimeOfDay(seconds []uint, minutes []uint, hours []uint) []string {
+	// Few distinct times of day read best as a list of clock times
+	if len(seconds) == 1 && len(minutes) == 1 && len(hours) <= 4 {
+		times := make([]string, len(hours))
+		for i, hour := range hours {
+			times[i] = clockTime(hour, minutes[0], seconds[0])
+		}
+
+		return []string{"at " + joinEnglish(times)}
+	}
+
+	var segments []string
+	if len(seconds) != 1 || seconds[0] != 0 {
+		segments = append(segments, describeField(seconds, secondField, ""))
+	}
+
+	switch {
+	case isAllValues(minutes, minuteField) && len(segments) == 0:
+		segments = append(segments, "every minute")
+	case isAllValues(minutes, minuteField):
+	default:
+		segments = append(segments, describeField(minutes, minuteField, " past the hour"))
+	}
+
+	if isAllValues(hours, hourField) {
+		return segments
+	}
+
+	if len(hours) == 1 {
+		return append(segments, fmt.Sprintf("between %s and %s", clockTime(hours[0], 0, 0), clockTime(hours[0], 59, 0)))
+	}
+
+	if first, last, ok := consecutive(hours); ok {
+		return append(segments, fmt.Sprintf("between %s and %s", clockTime(first, 0, 0), clockTime(last, 59, 0)))
+	}
+
+	return append(segments, describeField(hours, hourField, ""))
+}
+
+func describeDays(spec *cron.SpecSchedule) string {
+	var days []string
+	if spec.Dom&starBit == 0 {
+		days = append(days, describeField(fieldValues(spec.Dom, domField), domField, " of the month"))
+	}
+
+	if spec.Dow&starBit == 0 {
+		days = append(days, describeWeekdays(fieldValues(spec.Dow, dowField)))
+	}
+
+	// Both fields being restricted match either of them, see robfig/cron
+	return strings.Join(days, " or ")
+}
+
+func describeWeekdays(weekdays []uint) string {
+	if first, last, ok := consecutive(weekdays); ok && last-first >= 2 {
+		return fmt.Sprintf("%s through %s", time.Weekday(first), time.Weekday(last))
+	}
+
+	names := make([]string, len(weekdays))
+	for i, weekday := range weekdays {
+		names[i] = time.Weekday(weekday).String()
+	}
+
+	return "on " + joinEnglish(names)
+}
+
+func describeMonths(months []uint) string {
+	if isAllValues(months, monthField) {
+		return ""
+	}
+
+	if first, last, ok := consecutive(months); ok {
+		return fmt.Sprintf("%s through %s", time.Month(first), time.Month(last))
+	}
+
+	if step, ok := arithmetic(months); ok && months[0] == monthField.min && months[len(months)-1]+step > monthField.max {
+		return fmt.Sprintf("every %d months", step)
+	}
+
+	names := make([]string, len(months))
+	for i, month := range months {
+		names[i] = time.Month(month).String()
+	}
+
+	return "in " + joinEnglish(names)
+}
+
+// describeField describes the values of a single field, e.g. "every 5 minutes",
+// "at minute 30", "every minute from 0 through 15" or "at minutes 0, 15 and 45".
+func describeField(values []uint, field cronField, suffix string) string {
+	prefix := "at "
+	if field == domField {
+		prefix = "on "
+	}
+
+	if isAllValues(values, field) {
+		return "every " + field.unit
+	}
+
+	if len(values) == 1 {
+		return fmt.Sprintf("%s%s %d%s", prefix, field.unit, values[0], suffix)
+	}
+
+	if step, ok := arithmetic(values); ok && step > 1 {
+		// Steps starting at the lower bound, and running up to the upper bound
+		if values[0] == field.min && values[len(values)-1]+step > field.max {
+			return fmt.Sprintf("every %d %ss", step, field.unit)
+		}
+
+		if len(values) > 2 {
+			return fmt.Sprintf("every %d %ss from %d through %d%s", step, field.unit, values[0], values[len(values)-1], suffix)
+		}
+	}
+
+	if first, last, ok := consecutive(values); ok {
+		return fmt.Sprintf("every %s from %d through %d%s", field.unit, first, last, suffix)
+	}
+
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = fmt.Sprintf("%d", value)
+	}
+
+	return fmt.Sprintf("%s%ss %s%s", prefix, field.unit, joinEnglish(formatted), suffix)
+}
+
+// fieldValues returns all values set in the bits of a field.
+func fieldValues(bits uint64, field cronField) []uint {
+	var values []uint
+	for value := field.min; value <= field.max; value++ {
+		if bits&(1<<value) != 0 {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func isAllValues(values []uint, field cronField) bool {
+	return uint(len(values)) == field.max-field.min+1
+}
+
+// arithmetic returns the step, if the values form an arithmetic progression.
+func arithmetic(values []uint) (uint, bool) {
+	if len(values) < 2 {
+		return 0, false
+	}
+
+	step := values[1] - values[0]
+	for i := 2; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return 0, false
+		}
+	}
+
+	return step, true
+}
+
+// consecutive returns the bounds, if the values form a range of at least two values.
+func consecutive(values []uint) (uint, uint, bool) {
+	if step, ok := arithmetic(values); !ok || step != 1 {
+		return 0, 0, false
+	}
+
+	return values[0], values[len(values)-1], true
+}
+
+func clockTime(hour uint, minute uint, second uint) string {
+	if second == 0 {
+		return fmt.Sprintf("%02d:%02d", hour, minute)
+	}
+
+	return fmt.Sprintf("%02d:%02d:%02d", hour, minute, second)
+}
+
+// joinEnglish joins the given values, e.g. "a, b and c".
+func joinEnglish(values []string) string {
+	if len(values) <= 1 {
+		return strings.Join(values, "")
+	}
+
+	return strings.Join(values[:len(values)-1], ", ") + " and " + values[len(values)-1]
+}
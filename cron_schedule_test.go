@@ -0,0 +1,115 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"testing"
+	"time"
+)
+
+func Test_CronSchedule(t *testing.T) {
+	t.Run("malformed-expression", parseScheduleMalformedTest)
+
+	t.Run("preview", parseSchedulePreviewTest)
+
+	t.Run("preview-non-positive", parseSchedulePreviewNonPositiveTest)
+
+	t.Run("describe", parseScheduleDescribeTest)
+}
+
+func parseScheduleMalformedTest(t *testing.T) {
+	// when
+	schedule, err := crontask.ParseSchedule("aint-work")
+
+	// then
+	if err == nil {
+		t.Error("Expected error, but none occurred")
+	}
+
+	if schedule != nil {
+		t.Error("Expected no schedule being returned, but was")
+	}
+}
+
+func parseSchedulePreviewTest(t *testing.T) {
+	// given
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database not available: %s", err)
+	}
+
+	schedule, err := crontask.ParseSchedule("0 30 9 * * MON-FRI")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// when
+	preview := schedule.Preview(time.Date(2026, time.October, 23, 12, 0, 0, 0, time.UTC), 3, berlin)
+
+	// then
+	expected := []time.Time{
+		time.Date(2026, time.October, 26, 9, 30, 0, 0, berlin),
+		time.Date(2026, time.October, 27, 9, 30, 0, 0, berlin),
+		time.Date(2026, time.October, 28, 9, 30, 0, 0, berlin),
+	}
+
+	if len(preview) != len(expected) {
+		t.Fatalf("expected %d activations, got %d", len(expected), len(preview))
+	}
+
+	for i := range expected {
+		if !preview[i].Equal(expected[i]) || preview[i].Location() != berlin {
+			t.Errorf("expected activation %d at %s, got %s", i, expected[i], preview[i])
+		}
+	}
+}
+
+func parseSchedulePreviewNonPositiveTest(t *testing.T) {
+	// given
+	schedule, err := crontask.ParseSchedule("0 30 9 * * MON-FRI")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, n := range []int{0, -1} {
+		// when
+		preview := schedule.Preview(time.Date(2026, time.October, 23, 12, 0, 0, 0, time.UTC), n, nil)
+
+		// then
+		if preview == nil || len(preview) != 0 {
+			t.Errorf("expected an empty preview for n = %d, got %v", n, preview)
+		}
+	}
+}
+
+func parseScheduleDescribeTest(t *testing.T) {
+	expectations := map[string]string{
+		"0 0 2 * * *":            "At 02:00",
+		"*/5 * * * *":            "Every 5 minutes",
+		"0 */5 9-17 * * MON-FRI": "Every 5 minutes, between 09:00 and 17:59, Monday through Friday",
+		"30 * * * *":             "At minute 30 past the hour",
+		"*/10 * * * * *":         "Every 10 seconds",
+		"0 0 9,12,18 * * *":      "At 09:00, 12:00 and 18:00",
+		"0 0 0 1 1 *":            "At 00:00, on day 1 of the month, in January",
+		"0 0 0 1,15 * MON":       "At 00:00, on days 1 and 15 of the month or on Monday",
+		"0 0 0 1 */3 *":          "At 00:00, on day 1 of the month, every 3 months",
+		"@weekly":                "At 00:00, on Sunday",
+		"@every 1h30m":           "Every 1h30m0s",
+	}
+
+	for expression, expected := range expectations {
+		// given
+		schedule, err := crontask.ParseSchedule(expression)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", expression, err)
+		}
+
+		// when
+		description := schedule.Describe()
+
+		// then
+		if description != expected {
+			t.Errorf("expected description %q for %q, got %q", expected, expression, description)
+		}
+	}
+}
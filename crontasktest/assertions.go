@@ -0,0 +1,115 @@
+package crontasktest
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"sort"
+	"time"
+)
+
+// Execution is a single execution of a task function, as recorded by the harness.
+type Execution struct {
+	// Instance is the ID of the instance, which executed the task function.
+	Instance int
+
+	Task string
+	Slot time.Time
+
+	// Start and End are the times of the fake clock, at which the
+	// task function was called and returned.
+	Start time.Time
+	End   time.Time
+
+	// Err is the error returned by the task function.
+	Err error
+
+	// Result is the result reported by the task function - see crontask.SetSummary.
+	Result crontask.TaskResult
+
+	// Finished is true, if the task function returned already.
+	Finished bool
+
+	// Concurrent is true, if another instance executed the same
+	// task while this execution was running.
+	Concurrent bool
+}
+
+// AssertExactlyOncePerSlot fails the test, if the task with the given name was
+// executed more than once for any slot - or never at all. If slots are given,
+// the test is also failed if any of them was not executed, or if any other slot
+// was executed. Returns true, if the assertion holds.
+func (harness *Harness) AssertExactlyOncePerSlot(name string, slots ...time.Time) bool {
+	harness.t.Helper()
+
+	instances := map[time.Time][]int{}
+	for _, execution := range harness.Executions(name) {
+		slot := execution.Slot.UTC()
+		instances[slot] = append(instances[slot], execution.Instance)
+	}
+
+	if len(instances) == 0 && len(slots) == 0 {
+		harness.t.Errorf("expected task %q to be executed, but it never was", name)
+		return false
+	}
+
+	holds := true
+	for _, slot := range sortedSlots(instances) {
+		if executed := instances[slot]; len(executed) > 1 {
+			harness.t.Errorf("expected task %q to be executed once for slot %s, but it was executed %d times by instances %v", name, slot, len(executed), executed)
+			holds = false
+		}
+	}
+
+	if len(slots) == 0 {
+		return holds
+	}
+
+	expected := map[time.Time]bool{}
+	for _, slot := range slots {
+		slot = slot.UTC()
+		expected[slot] = true
+
+		if _, ok := instances[slot]; !ok {
+			harness.t.Errorf("expected task %q to be executed for slot %s, but it was not", name, slot)
+			holds = false
+		}
+	}
+
+	for _, slot := range sortedSlots(instances) {
+		if !expected[slot] {
+			harness.t.Errorf("expected task %q not to be executed for slot %s, but it was", name, slot)
+			holds = false
+		}
+	}
+
+	return holds
+}
+
+// AssertNoConcurrentExecutions fails the test, if two instances executed the
+// task with the given name at the same time. Returns true, if the assertion holds.
+func (harness *Harness) AssertNoConcurrentExecutions(name string) bool {
+	harness.t.Helper()
+
+	holds := true
+	for _, execution := range harness.Executions(name) {
+		if execution.Concurrent {
+			harness.t.Errorf("expected task %q not to be executed concurrently, but instance %d executed slot %s alongside another instance", name, execution.Instance, execution.Slot)
+			holds = false
+		}
+	}
+
+	return holds
+}
+
+func sortedSlots(instances map[time.Time][]int) []time.Time {
+	slots := make([]time.Time, 0, len(instances))
+	for slot := range instances {
+		slots = append(slots, slot)
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Before(slots[j])
+	})
+
+	return slots
+}
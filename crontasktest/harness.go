@@ -0,0 +1,383 @@
+// Package crontasktest provides a harness for testing task functions of
+// synchronized cron tasks. The harness runs any number of competing instances
+// against an in-memory redis, and drives their schedules, heartbeats and
+// timeouts via a fake clock - so no real redis is required, and no real
+// time has to pass:
+//
+//	harness := crontasktest.New(t)
+//	harness.StartInstances(3, someFunc,
+//	    crontask.CronExpression("0 * * * * *"),
+//	    crontask.CompletionHold(30*time.Second),
+//	)
+//
+//	harness.Advance(5 * time.Minute)
+//
+//	harness.AssertExactlyOncePerSlot(crontask.DefaultName)
+//	harness.AssertNoConcurrentExecutions(crontask.DefaultName)
+//
+// Lock loss and backend failures can be injected via Harness.LoseLock and
+// Instance.FailBackend.
+package crontasktest
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultQuietPeriod is the default real time span, for which the
+	// instances must show no activity to be considered settled.
+	DefaultQuietPeriod = 25 * time.Millisecond
+
+	// DefaultSettleTimeout is the default real time span, after which
+	// the test is failed if the instances did not settle yet.
+	DefaultSettleTimeout = 10 * time.Second
+)
+
+// DefaultStart is the default time, at which the fake clock of a harness starts.
+var DefaultStart = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness runs instances of synchronized cron tasks against an in-memory
+// redis, and records all executions of their task functions.
+type Harness struct {
+	t testing.TB
+
+	clock  *clock.Fake
+	server *miniredis.Miniredis
+	client *redis.Client
+
+	logger        *logrus.Logger
+	quietPeriod   time.Duration
+	settleTimeout time.Duration
+
+	// activity counts calls to the backend, as well as
+	// started and finished executions of task functions.
+	activity int64
+
+	mutex      sync.Mutex
+	instances  []*Instance
+	executions []*Execution
+	running    map[string][]*Execution
+}
+
+// NewWithOptions creates a new test harness. All instances are stopped, and the
+// in-memory redis is shut down once the test finishes.
+func NewWithOptions(t testing.TB, options *Options) *Harness {
+	t.Helper()
+
+	if options.Logger == nil {
+		// Create a "noop" logger, so we don't have to check for
+		// the logger being nil
+		logger := logrus.New()
+		logger.Out = io.Discard
+
+		options.Logger = logger
+	}
+
+	if options.Start.IsZero() {
+		options.Start = DefaultStart
+	}
+
+	if options.QuietPeriod <= 0 {
+		options.QuietPeriod = DefaultQuietPeriod
+	}
+
+	if options.SettleTimeout <= 0 {
+		options.SettleTimeout = DefaultSettleTimeout
+	}
+
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start in-memory redis: %s", err)
+	}
+
+	// The clock of redis follows the fake clock, so locks expire in fake time
+	server.SetTime(options.Start)
+
+	harness := &Harness{
+		t: t,
+
+		clock:  clock.NewFake(options.Start),
+		server: server,
+		client: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+
+		logger:        options.Logger,
+		quietPeriod:   options.QuietPeriod,
+		settleTimeout: options.SettleTimeout,
+
+		running: map[string][]*Execution{},
+	}
+
+	t.Cleanup(harness.close)
+
+	return harness
+}
+
+// New creates a new test harness. All instances are stopped, and the
+// in-memory redis is shut down once the test finishes.
+func New(t testing.TB, setters ...Option) *Harness {
+	t.Helper()
+
+	args := &Options{
+		Start: DefaultStart,
+
+		QuietPeriod:   DefaultQuietPeriod,
+		SettleTimeout: DefaultSettleTimeout,
+	}
+
+	for _, setter := range setters {
+		setter(args)
+	}
+
+	return NewWithOptions(t, args)
+}
+
+// Clock returns the fake clock, on which all instances run.
+func (harness *Harness) Clock() *clock.Fake {
+	return harness.clock
+}
+
+// Client returns a client of the in-memory redis, which is not subject to
+// injected backend failures - e.g. to pause tasks via crontask.Pause.
+func (harness *Harness) Client() *redis.Client {
+	return harness.client
+}
+
+// StartInstance starts a new instance of a synchronized cron task, with the
+// given task function and options. The fake clock and the logger of the
+// harness are applied before the given options. The test is failed, if the
+// options are invalid.
+func (harness *Harness) StartInstance(taskFunc crontask.TaskFunc, setters ...crontask.TaskOption) *Instance {
+	harness.t.Helper()
+
+	harness.mutex.Lock()
+	id := len(harness.instances)
+	harness.mutex.Unlock()
+
+	instance := &Instance{
+		ID:      id,
+		backend: &backend{client: harness.client, activity: &harness.activity},
+	}
+
+	setters = append([]crontask.TaskOption{
+		crontask.Clock(harness.clock),
+		crontask.Logger(harness.logger),
+	}, setters...)
+
+	task, err := crontask.NewSynchronizedCronTask(instance.backend, harness.wrap(id, taskFunc), setters...)
+	if err != nil {
+		harness.t.Fatalf("failed to start instance %d: %s", id, err)
+	}
+
+	instance.Task = task
+	instance.stopTimeout = harness.settleTimeout
+
+	harness.mutex.Lock()
+	harness.instances = append(harness.instances, instance)
+	harness.mutex.Unlock()
+
+	return instance
+}
+
+// StartInstances starts the given number of competing instances of a synchronized
+// cron task. See Harness.StartInstance for details.
+func (harness *Harness) StartInstances(count int, taskFunc crontask.TaskFunc, setters ...crontask.TaskOption) []*Instance {
+	harness.t.Helper()
+
+	instances := make([]*Instance, count)
+	for i := range instances {
+		instances[i] = harness.StartInstance(taskFunc, setters...)
+	}
+
+	return instances
+}
+
+// Advance advances the fake clock by the given duration. See Harness.AdvanceTo for details.
+func (harness *Harness) Advance(d time.Duration) {
+	harness.t.Helper()
+
+	harness.AdvanceTo(harness.clock.Now().Add(d))
+}
+
+// AdvanceTo advances the fake clock to the given time. The clock is advanced step by
+// step to everything waiting on it - such as firings, heartbeats and timeouts - and
+// the instances are allowed to settle after every step.
+func (harness *Harness) AdvanceTo(target time.Time) {
+	harness.t.Helper()
+
+	harness.Settle()
+
+	for {
+		next, ok := harness.clock.Next()
+		if !ok || next.After(target) {
+			break
+		}
+
+		harness.step(next)
+		harness.Settle()
+	}
+
+	harness.step(target)
+	harness.Settle()
+}
+
+// step advances the fake clock and the clock of redis to the given time.
+func (harness *Harness) step(to time.Time) {
+	if from := harness.clock.Now(); to.After(from) {
+		harness.server.FastForward(to.Sub(from))
+		harness.server.SetTime(to)
+	}
+
+	harness.clock.AdvanceTo(to)
+}
+
+// Settle blocks until the instances settled, i.e. neither called the backend, nor
+// started or finished an execution - nor started or stopped waiting on the fake clock
+// for the quiet period. The test is failed, if they do not settle in time.
+func (harness *Harness) Settle() {
+	harness.t.Helper()
+
+	deadline := time.Now().Add(harness.settleTimeout)
+	quietSince := time.Now()
+	activity, waiters := atomic.LoadInt64(&harness.activity), harness.clock.Waiters()
+
+	for {
+		time.Sleep(time.Millisecond)
+
+		currentActivity, currentWaiters := atomic.LoadInt64(&harness.activity), harness.clock.Waiters()
+		if currentActivity != activity || currentWaiters != waiters {
+			activity, waiters = currentActivity, currentWaiters
+			quietSince = time.Now()
+		}
+
+		if time.Since(quietSince) >= harness.quietPeriod {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			harness.t.Fatalf("instances did not settle within %s", harness.settleTimeout)
+		}
+	}
+}
+
+// LoseLock removes the lock of the task with the given name from redis, as if it
+// expired. The instance holding it loses its leadership upon its next heartbeat.
+// Returns true, if the lock was held by any instance.
+func (harness *Harness) LoseLock(name string) bool {
+	harness.t.Helper()
+
+	released, err := crontask.ForceRelease(context.Background(), harness.client, name)
+	if err != nil {
+		harness.t.Errorf("failed to remove lock of task %q: %s", name, err)
+	}
+
+	return released
+}
+
+// Executions returns all executions of the task with the given name, in
+// the order they were started.
+func (harness *Harness) Executions(name string) []Execution {
+	harness.mutex.Lock()
+	defer harness.mutex.Unlock()
+
+	var executions []Execution
+	for _, execution := range harness.executions {
+		if execution.Task == name {
+			executions = append(executions, *execution)
+		}
+	}
+
+	return executions
+}
+
+// wrap wraps the task function of the given instance, so its executions - and
+// their reported results - are recorded.
+func (harness *Harness) wrap(instance int, taskFunc crontask.TaskFunc) crontask.TaskFunc {
+	return func(ctx context.Context, task crontask.Task) error {
+		slot, _ := crontask.SlotFromContext(ctx)
+
+		execution := &Execution{
+			Instance: instance,
+			Task:     task.Name(),
+			Slot:     slot,
+			Start:    harness.clock.Now(),
+		}
+
+		ctx = crontask.WithResultRecorder(ctx)
+
+		harness.started(execution)
+		err := taskFunc(ctx, task)
+
+		result, _ := crontask.ResultFromContext(ctx)
+		harness.finished(execution, result, err)
+
+		return err
+	}
+}
+
+// started records the start of an execution. If other instances are executing
+// the same task right now, all of these executions are marked as concurrent.
+func (harness *Harness) started(execution *Execution) {
+	atomic.AddInt64(&harness.activity, 1)
+
+	harness.mutex.Lock()
+	defer harness.mutex.Unlock()
+
+	running := harness.running[execution.Task]
+	for _, other := range running {
+		other.Concurrent, execution.Concurrent = true, true
+	}
+
+	harness.running[execution.Task] = append(running, execution)
+	harness.executions = append(harness.executions, execution)
+}
+
+// finished records the end of an execution.
+func (harness *Harness) finished(execution *Execution, result crontask.TaskResult, err error) {
+	atomic.AddInt64(&harness.activity, 1)
+
+	harness.mutex.Lock()
+	defer harness.mutex.Unlock()
+
+	execution.End = harness.clock.Now()
+	execution.Err = err
+	execution.Result = result
+	execution.Finished = true
+
+	running := harness.running[execution.Task]
+	for i, candidate := range running {
+		if candidate == execution {
+			harness.running[execution.Task] = append(running[:i], running[i+1:]...)
+			break
+		}
+	}
+}
+
+// close stops all instances - canceling their running executions - and
+// shuts down the in-memory redis.
+func (harness *Harness) close() {
+	harness.mutex.Lock()
+	instances := harness.instances
+	harness.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, instance := range instances {
+		instance.stop(ctx)
+	}
+
+	_ = harness.client.Close()
+	harness.server.Close()
+}
@@ -0,0 +1,55 @@
+package crontasktest
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"time"
+)
+
+// Options bundles all available configuration
+// properties for a test harness.
+type Options struct {
+	Start time.Time
+
+	Logger *logrus.Logger
+
+	QuietPeriod   time.Duration
+	SettleTimeout time.Duration
+}
+
+// Option represents an option for a test harness.
+type Option func(*Options)
+
+// Start sets the time, at which the fake clock of the harness starts.
+// The default is crontasktest.DefaultStart.
+func Start(start time.Time) Option {
+	return func(c *Options) {
+		c.Start = start
+	}
+}
+
+// Logger sets the logger, which is passed on to all instances.
+// The default is nil, which discards all logs.
+func Logger(logger *logrus.Logger) Option {
+	return func(c *Options) {
+		c.Logger = logger
+	}
+}
+
+// QuietPeriod sets the real time span, for which the instances must show no
+// activity to be considered settled. Slow machines might require a longer period.
+// The default is crontasktest.DefaultQuietPeriod.
+func QuietPeriod(quietPeriod time.Duration) Option {
+	return func(c *Options) {
+		c.QuietPeriod = quietPeriod
+	}
+}
+
+// SettleTimeout sets the real time span, after which the test is failed if
+// the instances did not settle yet - e.g. as a task function is busy looping.
+// The default is crontasktest.DefaultSettleTimeout.
+func SettleTimeout(settleTimeout time.Duration) Option {
+	return func(c *Options) {
+		c.SettleTimeout = settleTimeout
+	}
+}
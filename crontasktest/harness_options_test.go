@@ -0,0 +1,72 @@
+package crontasktest_test
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/crontasktest"
+
+	"github.com/sirupsen/logrus"
+
+	"testing"
+	"time"
+)
+
+// Tests that the Start option correctly applies.
+func Test_HarnessOption_Start(t *testing.T) {
+	// given
+	start := time.Date(2040, time.June, 1, 0, 0, 0, 0, time.UTC)
+	option := crontasktest.Start(start)
+	options := &crontasktest.Options{Start: crontasktest.DefaultStart}
+
+	// when
+	option(options)
+
+	// then
+	if !options.Start.Equal(start) {
+		t.Errorf("start not correctly applied, got %s", options.Start)
+	}
+}
+
+// Tests that the Logger option correctly applies.
+func Test_HarnessOption_Logger(t *testing.T) {
+	// given
+	logger := logrus.New()
+	option := crontasktest.Logger(logger)
+	options := &crontasktest.Options{Logger: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Logger != logger {
+		t.Errorf("logger not correctly applied, got %v", options.Logger)
+	}
+}
+
+// Tests that the QuietPeriod option correctly applies.
+func Test_HarnessOption_QuietPeriod(t *testing.T) {
+	// given
+	option := crontasktest.QuietPeriod(time.Second)
+	options := &crontasktest.Options{QuietPeriod: crontasktest.DefaultQuietPeriod}
+
+	// when
+	option(options)
+
+	// then
+	if options.QuietPeriod != time.Second {
+		t.Errorf("quiet period not correctly applied, got %s", options.QuietPeriod)
+	}
+}
+
+// Tests that the SettleTimeout option correctly applies.
+func Test_HarnessOption_SettleTimeout(t *testing.T) {
+	// given
+	option := crontasktest.SettleTimeout(time.Minute)
+	options := &crontasktest.Options{SettleTimeout: crontasktest.DefaultSettleTimeout}
+
+	// when
+	option(options)
+
+	// then
+	if options.SettleTimeout != time.Minute {
+		t.Errorf("settle timeout not correctly applied, got %s", options.SettleTimeout)
+	}
+}
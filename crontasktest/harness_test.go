@@ -0,0 +1,376 @@
+package crontasktest_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/crontasktest"
+
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingT records errors of assertions, instead of failing the test.
+type recordingT struct {
+	testing.TB
+
+	mutex  sync.Mutex
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func noopTask(context.Context, crontask.Task) error {
+	return nil
+}
+
+func minute(minute int) time.Time {
+	return crontasktest.DefaultStart.Add(time.Duration(minute) * time.Minute)
+}
+
+// Tests that competing instances execute every slot exactly once. As the executions
+// are instant, lagging instances would execute the slot again without a completion hold.
+func Test_Harness_ExactlyOncePerSlot(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	harness.StartInstances(3, noopTask,
+		crontask.CronExpression("0 * * * * *"),
+		crontask.CompletionHold(30*time.Second),
+	)
+
+	// when
+	harness.Advance(3 * time.Minute)
+
+	// then
+	harness.AssertExactlyOncePerSlot(crontask.DefaultName, minute(1), minute(2), minute(3))
+	harness.AssertNoConcurrentExecutions(crontask.DefaultName)
+}
+
+// Tests that heartbeats and leadership timeouts are driven by the fake clock.
+func Test_Harness_LeadershipTimeout(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+
+	causes := make(chan error, 1)
+	harness.StartInstances(2,
+		func(ctx context.Context, task crontask.Task) error {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+		crontask.LeadershipTimeout(30*time.Second),
+	)
+
+	// when
+	harness.Advance(time.Minute + 30*time.Second)
+
+	// then
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, crontask.ErrLeadershipTimeout) {
+			t.Errorf("expected leadership timeout, got %v", cause)
+		}
+	default:
+		t.Fatal("expected execution to be canceled after the leadership timeout")
+	}
+
+	harness.AssertExactlyOncePerSlot(crontask.DefaultName, minute(1))
+}
+
+// Tests that losing the lock cancels the execution upon the next heartbeat.
+func Test_Harness_LoseLock(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+
+	causes := make(chan error, 1)
+	instance := harness.StartInstance(
+		func(ctx context.Context, task crontask.Task) error {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+		crontask.LeadershipTimeout(30*time.Second),
+	)
+
+	harness.Advance(time.Minute)
+
+	// when
+	released := harness.LoseLock(crontask.DefaultName)
+	harness.Advance(crontask.DefaultLockHeartbeat)
+
+	// then
+	if !released {
+		t.Error("expected lock to be held while executing")
+	}
+
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, crontask.ErrLockLost) {
+			t.Errorf("expected lost lock, got %v", cause)
+		}
+	default:
+		t.Fatal("expected execution to be canceled after the lock was lost")
+	}
+
+	// Losing the lock is a failure, even though the election was won
+	if health := instance.Task.Health(context.Background()); health.ConsecutiveFailures != 1 || health.LastFailure == "" {
+		t.Errorf("expected lost lock to be recorded as failure, got %+v", health)
+	}
+}
+
+// Tests that an instance with a failing backend never executes, while others take over.
+func Test_Harness_FailBackend(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	instances := harness.StartInstances(2, noopTask,
+		crontask.CronExpression("0 * * * * *"),
+		crontask.CompletionHold(30*time.Second),
+	)
+
+	// when
+	instances[0].FailBackend()
+	harness.Advance(2 * time.Minute)
+
+	instances[0].RecoverBackend()
+	instances[1].FailBackend()
+	harness.Advance(time.Minute)
+
+	// then
+	harness.AssertExactlyOncePerSlot(crontask.DefaultName, minute(1), minute(2), minute(3))
+
+	for _, execution := range harness.Executions(crontask.DefaultName) {
+		if expected := 1; execution.Slot.Before(minute(3)) && execution.Instance != expected {
+			t.Errorf("expected slot %s to be executed by instance %d, got %d", execution.Slot, expected, execution.Instance)
+		}
+
+		if expected := 0; execution.Slot.Equal(minute(3)) && execution.Instance != expected {
+			t.Errorf("expected slot %s to be executed by instance %d, got %d", execution.Slot, expected, execution.Instance)
+		}
+	}
+}
+
+// Tests that competing instances execute every shard of every slot exactly once, and
+// that dependent tasks only run once the last shard of a slot was completed.
+func Test_Harness_Shards(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+
+	var mutex sync.Mutex
+	shards := map[time.Time][]int{}
+	completed := map[time.Time]int{}
+
+	harness.StartInstances(3,
+		func(ctx context.Context, task crontask.Task) error {
+			slot, _ := crontask.SlotFromContext(ctx)
+			shard, _ := crontask.ShardFromContext(ctx)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			shards[slot] = append(shards[slot], shard.Index)
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+		crontask.Shards(4),
+	)
+
+	harness.StartInstance(
+		func(ctx context.Context, task crontask.Task) error {
+			slot, _ := crontask.SlotFromContext(ctx)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			completed[slot] = len(shards[slot])
+			return nil
+		},
+		crontask.TaskName("downstream"),
+		crontask.CronExpression("0 * * * * *"),
+		crontask.DependsOn(crontask.DefaultName),
+	)
+
+	// when
+	harness.Advance(2*time.Minute + 5*time.Second)
+
+	// then
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, slot := range []time.Time{minute(1), minute(2)} {
+		counts := make([]int, 4)
+		for _, index := range shards[slot] {
+			counts[index]++
+		}
+
+		for index, count := range counts {
+			if count != 1 {
+				t.Errorf("expected shard %d of slot %s to be executed exactly once, got %d", index, slot, count)
+			}
+		}
+
+		if count, ok := completed[slot]; !ok || count != 4 {
+			t.Errorf("expected downstream task to run for slot %s after all 4 shards, got %d (%t)", slot, count, ok)
+		}
+	}
+}
+
+// Tests that a shard abandoned by a dead instance is picked up by another
+// instance, once the shard lock of the dead instance expired.
+func Test_Harness_AbandonedShard(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+
+	var attempts int32
+	instances := harness.StartInstances(2,
+		func(ctx context.Context, task crontask.Task) error {
+			// The first execution of shard 0 hangs, until its instance is declared dead
+			if shard, _ := crontask.ShardFromContext(ctx); shard.Index == 0 && atomic.AddInt32(&attempts, 1) == 1 {
+				<-ctx.Done()
+				return context.Cause(ctx)
+			}
+
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+		crontask.Shards(2),
+	)
+
+	harness.Advance(time.Minute)
+
+	dead := -1
+	for _, execution := range harness.Executions(crontask.DefaultName) {
+		if !execution.Finished {
+			dead = execution.Instance
+		}
+	}
+
+	if dead < 0 {
+		t.Fatal("expected shard 0 to be executing")
+	}
+
+	// when
+	instances[dead].FailBackend()
+	harness.Advance(crontask.DefaultLockTimeout + 2*crontask.DefaultLockHeartbeat)
+
+	// then
+	if count := atomic.LoadInt32(&attempts); count != 2 {
+		t.Fatalf("expected shard 0 to be executed twice, got %d", count)
+	}
+
+	executions := harness.Executions(crontask.DefaultName)
+	if len(executions) != 3 {
+		t.Fatalf("expected 3 executions, got %d", len(executions))
+	}
+
+	reclaimed := executions[2]
+	if reclaimed.Instance == dead || !reclaimed.Finished || reclaimed.Err != nil {
+		t.Errorf("expected shard 0 to be completed by another instance than %d, got %+v", dead, reclaimed)
+	}
+}
+
+// Tests that firings of paused tasks are skipped, until the task is resumed.
+func Test_Harness_Pause(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	harness.StartInstance(noopTask, crontask.CronExpression("0 * * * * *"))
+
+	if err := crontask.Pause(context.Background(), harness.Client(), crontask.DefaultName); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	state, err := crontask.InspectTask(context.Background(), harness.Client(), crontask.DefaultName)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The time of pausing is taken from the redis server, which follows the fake clock
+	if !state.PausedSince.Equal(harness.Clock().Now().Truncate(time.Millisecond)) {
+		t.Errorf("expected task to be paused since %s, but was since %s", harness.Clock().Now(), state.PausedSince)
+	}
+
+	harness.Advance(2 * time.Minute)
+
+	// when
+	if err := crontask.Resume(context.Background(), harness.Client(), crontask.DefaultName); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	harness.Advance(time.Minute)
+
+	// then
+	harness.AssertExactlyOncePerSlot(crontask.DefaultName, minute(3))
+}
+
+// Tests that results reported by task functions are recorded.
+func Test_Harness_Result(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	harness.StartInstance(
+		func(ctx context.Context, task crontask.Task) error {
+			crontask.SetSummary(ctx, "processed 1234 rows")
+			return nil
+		},
+		crontask.CronExpression("0 * * * * *"),
+	)
+
+	// when
+	harness.Advance(time.Minute)
+
+	// then
+	executions := harness.Executions(crontask.DefaultName)
+	if len(executions) != 1 {
+		t.Fatalf("expected one execution, got %d", len(executions))
+	}
+
+	if expected := "processed 1234 rows"; executions[0].Result.Summary != expected {
+		t.Errorf("expected result summary %q, got %q", expected, executions[0].Result.Summary)
+	}
+}
+
+// Tests that the assertions fail, if instances do not compete for the same lock.
+func Test_Harness_Assertions(t *testing.T) {
+	// given
+	recorder := &recordingT{TB: t}
+	harness := crontasktest.New(recorder)
+
+	for _, namespace := range []string{"a", "b"} {
+		harness.StartInstance(
+			func(ctx context.Context, task crontask.Task) error {
+				<-ctx.Done()
+				return nil
+			},
+			crontask.CronExpression("0 * * * * *"),
+			crontask.LeadershipTimeout(30*time.Second),
+			crontask.TaskKeyspace(crontask.Keyspace{Namespace: namespace}),
+		)
+	}
+
+	harness.Advance(time.Minute + 30*time.Second)
+
+	// when
+	exactlyOnce := harness.AssertExactlyOncePerSlot(crontask.DefaultName, minute(1), minute(2))
+	notConcurrent := harness.AssertNoConcurrentExecutions(crontask.DefaultName)
+	neverExecuted := harness.AssertExactlyOncePerSlot("unknown")
+
+	// then
+	if exactlyOnce || notConcurrent || neverExecuted {
+		t.Errorf("expected all assertions to fail, got %t, %t and %t", exactlyOnce, notConcurrent, neverExecuted)
+	}
+
+	// Two executions of slot 1, slot 2 missing, two concurrent executions and no executions at all
+	if len(recorder.errors) != 5 {
+		t.Errorf("expected 5 errors, got %d: %q", len(recorder.errors), recorder.errors)
+	}
+}
@@ -0,0 +1,116 @@
+package crontasktest
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBackendUnavailable is returned by all calls to the backend of an
+// instance, while its backend is failing - see Instance.FailBackend.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// Instance is a single running instance of a synchronized cron task, competing
+// with all other instances of the harness.
+type Instance struct {
+	// ID is the index of the instance, in the order instances were started.
+	ID int
+
+	Task *crontask.SynchronizedCronTask
+
+	backend     *backend
+	stopTimeout time.Duration
+	stopped     int32
+}
+
+// FailBackend fails all calls of the instance to the backend with
+// ErrBackendUnavailable, until the backend is recovered. Calls of
+// other instances are not affected.
+func (instance *Instance) FailBackend() {
+	atomic.StoreInt32(&instance.backend.failing, 1)
+}
+
+// RecoverBackend recovers the backend of the instance, after it was failed.
+func (instance *Instance) RecoverBackend() {
+	atomic.StoreInt32(&instance.backend.failing, 0)
+}
+
+// Stop gracefully stops the instance. A running execution is canceled, if it
+// does not finish within the settle timeout of the harness.
+func (instance *Instance) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), instance.stopTimeout)
+	defer cancel()
+
+	instance.stop(ctx)
+}
+
+func (instance *Instance) stop(ctx context.Context) {
+	if atomic.CompareAndSwapInt32(&instance.stopped, 0, 1) {
+		instance.Task.Stop(ctx)
+	}
+}
+
+// backend is the redis client of a single instance, whose calls can be failed.
+type backend struct {
+	client *redis.Client
+
+	activity *int64
+	failing  int32
+}
+
+// call counts a call to the backend as activity, and returns
+// ErrBackendUnavailable if the backend is failing.
+func (backend *backend) call() error {
+	atomic.AddInt64(backend.activity, 1)
+
+	if atomic.LoadInt32(&backend.failing) == 1 {
+		return ErrBackendUnavailable
+	}
+
+	return nil
+}
+
+func (backend *backend) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if err := backend.call(); err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	return backend.client.SetNX(ctx, key, value, expiration)
+}
+
+func (backend *backend) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	if err := backend.call(); err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	return backend.client.Eval(ctx, script, keys, args...)
+}
+
+func (backend *backend) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if err := backend.call(); err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	return backend.client.EvalSha(ctx, sha1, keys, args...)
+}
+
+func (backend *backend) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	if err := backend.call(); err != nil {
+		return redis.NewBoolSliceResult(nil, err)
+	}
+
+	return backend.client.ScriptExists(ctx, hashes...)
+}
+
+func (backend *backend) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	if err := backend.call(); err != nil {
+		return redis.NewStringResult("", err)
+	}
+
+	return backend.client.ScriptLoad(ctx, script)
+}
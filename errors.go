@@ -0,0 +1,93 @@
+package crontask
+
+import (
+	"errors"
+)
+
+// The cause of the cancellation of the context passed to a TaskFunc (or
+// SingletonFunc) can be retrieved via context.Cause, and is one of
+// ErrStopped, ErrLeadershipTimeout or ErrLockLost.
+var (
+	// ErrStopped is the cause of executions, which were canceled as their
+	// synchronized cron task (or singleton) was stopped.
+	ErrStopped = errors.New("synchronized task stopped")
+
+	// ErrLeadershipTimeout is the cause of executions, which were canceled
+	// as the LeadershipTimeout of their synchronized cron task was reached.
+	ErrLeadershipTimeout = errors.New("leadership timeout reached")
+
+	// ErrLockLost is the cause of executions, which were canceled as their
+	// lock could not be renewed - so another instance might take over.
+	ErrLockLost = errors.New("leadership lock lost")
+
+	// ErrNotElected is matched by the errors of all firings, which did not
+	// gain leadership - e.g. as another instance holds the lock, or the
+	// synchronized cron task is paused.
+	ErrNotElected = errors.New("leadership not gained")
+)
+
+// electionError marks errors, which occurred before leadership was gained.
+type electionError struct {
+	err error
+}
+
+func (e electionError) Error() string {
+	return e.err.Error()
+}
+
+func (e electionError) Unwrap() error {
+	return e.err
+}
+
+func (e electionError) Is(target error) bool {
+	return target == ErrNotElected
+}
+
+// elected returns true, if the given error of an execution occurred
+// after leadership was gained - or there was no error at all.
+func elected(err error) bool {
+	return !errors.Is(err, ErrNotElected)
+}
+
+// remoteError is an error reported by another instance, which still
+// matches the exported error it was caused by - if any.
+type remoteError struct {
+	message string
+	cause   error
+}
+
+func (e remoteError) Error() string {
+	return e.message
+}
+
+func (e remoteError) Unwrap() error {
+	return e.cause
+}
+
+// causes are the exported errors, which are reported across instances -
+// in the order they are matched against.
+var causes = []error{ErrStopped, ErrLeadershipTimeout, ErrLockLost, ErrNotElected}
+
+// causeOf returns the exported error, the given error was caused by. If it
+// was not caused by any of them, nil is returned.
+func causeOf(err error) error {
+	for _, cause := range causes {
+		if errors.Is(err, cause) {
+			return cause
+		}
+	}
+
+	return nil
+}
+
+// causeWithMessage returns the exported error with the given message. If
+// there is none, nil is returned.
+func causeWithMessage(message string) error {
+	for _, cause := range causes {
+		if cause.Error() == message {
+			return cause
+		}
+	}
+
+	return nil
+}
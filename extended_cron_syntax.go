@@ -0,0 +1,330 @@
+package crontask
+
+import (
+	"github.com/robfig/cron/v3"
+
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExtendedLookahead is the maximum time span searched for the next activation
+// of an extended schedule. This matches the cut-off of robfig/cron.
+const maxExtendedLookahead = 5 * 366 * 24 * time.Hour
+
+var (
+	domParser = cron.NewParser(cron.Dom)
+	dowParser = cron.NewParser(cron.Dow)
+)
+
+var ordinals = []string{"first", "second", "third", "fourth", "fifth"}
+
+// ParseExtendedSchedule parses the given cron expression like ParseSchedule,
+// but additionally supports the Quartz-style tokens L, W and # in the day of
+// month and day of week fields:
+//
+//	L     - last day of the month (day of month), or Saturday (day of week)
+//	L-3   - third to last day of the month
+//	15W   - weekday nearest to the 15th of the month, within the same month
+//	LW    - last weekday of the month
+//	5L    - last Friday of the month
+//	2#2   - second Tuesday of the month
+//
+// Unlike Quartz, days of week are numbered from 0 (Sunday) to 6 (Saturday),
+// just like in the regular syntax. The ? token is supported by both syntaxes.
+func ParseExtendedSchedule(expression string) (*CronSchedule, error) {
+	schedule, err := parseExtendedCronExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CronSchedule{
+		expression: expression,
+		schedule:   schedule,
+	}, nil
+}
+
+// parseExtendedCronExpression parses a cron expression, which might contain
+// Quartz-style tokens. Expressions without such tokens are parsed as usual.
+func parseExtendedCronExpression(expression string) (cron.Schedule, error) {
+	spec := expression
+
+	prefix := ""
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if i := strings.Index(spec, " "); i >= 0 {
+			prefix, spec = spec[:i+1], strings.TrimSpace(spec[i:])
+		}
+	}
+
+	fields := strings.Fields(spec)
+	if strings.HasPrefix(spec, "@") || (len(fields) != 5 && len(fields) != 6) {
+		return cronParser.Parse(expression)
+	}
+
+	domIndex, dowIndex := len(fields)-3, len(fields)-1
+
+	domExpression := strings.ToUpper(fields[domIndex])
+	dowExpression := strings.ToUpper(fields[dowIndex])
+	if !strings.ContainsAny(domExpression, "LW") && !strings.ContainsAny(dowExpression, "L#") {
+		return cronParser.Parse(expression)
+	}
+
+	dom, err := parseDayItems(domExpression, parseDomItem)
+	if err != nil {
+		return nil, fmt.Errorf("day of month %q: %w", fields[domIndex], err)
+	}
+
+	dow, err := parseDayItems(dowExpression, parseDowItem)
+	if err != nil {
+		return nil, fmt.Errorf("day of week %q: %w", fields[dowIndex], err)
+	}
+
+	// All fields but the days are handled by robfig/cron
+	fields[domIndex], fields[dowIndex] = "*", "*"
+
+	base, err := cronParser.Parse(prefix + strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	return &extendedSchedule{
+		base: base.(*cron.SpecSchedule),
+		dom:  dom,
+		dow:  dow,
+	}, nil
+}
+
+// dayItem matches days of a single item of the day of month or day of week field.
+type dayItem struct {
+	matches     func(date time.Time) bool
+	description string
+}
+
+// parseDayItems parses all comma separated items of a day field. If
+// the field is unrestricted, nil is returned.
+func parseDayItems(expression string, parseItem func(item string) (dayItem, error)) ([]dayItem, error) {
+	if expression == "*" || expression == "?" {
+		return nil, nil
+	}
+
+	var items []dayItem
+	for _, item := range strings.Split(expression, ",") {
+		parsed, err := parseItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, parsed)
+	}
+
+	return items, nil
+}
+
+func parseDomItem(item string) (dayItem, error) {
+	switch {
+	case item == "L":
+		return dayItem{
+			matches:     func(date time.Time) bool { return date.Day() == lastDayOfMonth(date) },
+			description: "on the last day of the month",
+		}, nil
+	case item == "LW":
+		return dayItem{
+			matches: func(date time.Time) bool {
+				return date.Day() == nearestWeekday(date, lastDayOfMonth(date))
+			},
+			description: "on the last weekday of the month",
+		}, nil
+	case strings.HasPrefix(item, "L-"):
+		offset, err := strconv.Atoi(item[2:])
+		if err != nil || offset < 1 || offset > 30 {
+			return dayItem{}, fmt.Errorf("invalid offset from last day %q", item)
+		}
+
+		return dayItem{
+			matches:     func(date time.Time) bool { return date.Day() == lastDayOfMonth(date)-offset },
+			description: fmt.Sprintf("%d days before the last day of the month", offset),
+		}, nil
+	case strings.HasSuffix(item, "W"):
+		day, err := strconv.Atoi(item[:len(item)-1])
+		if err != nil || day < 1 || day > 31 {
+			return dayItem{}, fmt.Errorf("invalid day for nearest weekday %q", item)
+		}
+
+		return dayItem{
+			matches: func(date time.Time) bool {
+				return day <= lastDayOfMonth(date) && date.Day() == nearestWeekday(date, day)
+			},
+			description: fmt.Sprintf("on the weekday nearest to day %d of the month", day),
+		}, nil
+	}
+
+	schedule, err := domParser.Parse(item)
+	if err != nil {
+		return dayItem{}, err
+	}
+
+	bits := schedule.(*cron.SpecSchedule).Dom
+	return dayItem{
+		matches:     func(date time.Time) bool { return bits&(1<<uint(date.Day())) != 0 },
+		description: describeField(fieldValues(bits, domField), domField, " of the month"),
+	}, nil
+}
+
+func parseDowItem(item string) (dayItem, error) {
+	switch {
+	case item == "L":
+		// Quartz considers Saturday the last day of the week
+		return parseDowItem("6")
+	case strings.HasSuffix(item, "L"):
+		weekday, err := parseWeekday(item[:len(item)-1])
+		if err != nil {
+			return dayItem{}, err
+		}
+
+		return dayItem{
+			matches: func(date time.Time) bool {
+				return date.Weekday() == weekday && date.Day()+7 > lastDayOfMonth(date)
+			},
+			description: fmt.Sprintf("on the last %s of the month", weekday),
+		}, nil
+	case strings.Contains(item, "#"):
+		parts := strings.SplitN(item, "#", 2)
+
+		weekday, err := parseWeekday(parts[0])
+		if err != nil {
+			return dayItem{}, err
+		}
+
+		nth, err := strconv.Atoi(parts[1])
+		if err != nil || nth < 1 || nth > len(ordinals) {
+			return dayItem{}, fmt.Errorf("invalid occurrence of weekday %q", item)
+		}
+
+		return dayItem{
+			matches: func(date time.Time) bool {
+				return date.Weekday() == weekday && (date.Day()-1)/7+1 == nth
+			},
+			description: fmt.Sprintf("on the %s %s of the month", ordinals[nth-1], weekday),
+		}, nil
+	}
+
+	schedule, err := dowParser.Parse(item)
+	if err != nil {
+		return dayItem{}, err
+	}
+
+	bits := schedule.(*cron.SpecSchedule).Dow
+	return dayItem{
+		matches:     func(date time.Time) bool { return bits&(1<<uint(date.Weekday())) != 0 },
+		description: describeWeekdays(fieldValues(bits, dowField)),
+	}, nil
+}
+
+// parseWeekday parses a single day of week, given as a number or name.
+func parseWeekday(expression string) (time.Weekday, error) {
+	schedule, err := dowParser.Parse(expression)
+	if err != nil {
+		return 0, err
+	}
+
+	weekdays := fieldValues(schedule.(*cron.SpecSchedule).Dow, dowField)
+	if len(weekdays) != 1 {
+		return 0, fmt.Errorf("expected a single day of week, got %q", expression)
+	}
+
+	return time.Weekday(weekdays[0]), nil
+}
+
+func lastDayOfMonth(date time.Time) int {
+	return time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday nearest to the given day of the month
+// of the date, without crossing the boundaries of the month.
+func nearestWeekday(date time.Time, day int) int {
+	weekday := time.Date(date.Year(), date.Month(), day, 0, 0, 0, 0, time.UTC).Weekday()
+
+	switch {
+	case weekday == time.Saturday && day == 1:
+		return day + 2
+	case weekday == time.Saturday:
+		return day - 1
+	case weekday == time.Sunday && day == lastDayOfMonth(date):
+		return day - 2
+	case weekday == time.Sunday:
+		return day + 1
+	default:
+		return day
+	}
+}
+
+// extendedSchedule is a schedule, whose days are restricted by Quartz-style
+// tokens. All other fields are handled by its base schedule.
+type extendedSchedule struct {
+	base *cron.SpecSchedule
+
+	dom []dayItem
+	dow []dayItem
+}
+
+func (s *extendedSchedule) Next(t time.Time) time.Time {
+	location := s.base.Location
+	if location == time.Local {
+		location = t.Location()
+	}
+
+	originalLocation := t.Location()
+	t = t.In(location)
+
+	for limit := t.Add(maxExtendedLookahead); t.Before(limit); {
+		next := s.base.Next(t)
+		if next.IsZero() {
+			return next
+		}
+
+		next = next.In(location)
+		if s.matchesDay(next) {
+			return next.In(originalLocation)
+		}
+
+		// Continue right before the start of the following day
+		year, month, day := next.Date()
+		t = time.Date(year, month, day+1, 0, 0, 0, 0, location).Add(-time.Nanosecond)
+	}
+
+	return time.Time{}
+}
+
+// matchesDay checks if the date is matched by the day fields. Just like robfig/cron,
+// if both fields are restricted, a day matching either of them is matched.
+func (s *extendedSchedule) matchesDay(date time.Time) bool {
+	if s.dom == nil {
+		return anyDayItemMatches(s.dow, date)
+	}
+
+	if s.dow == nil {
+		return anyDayItemMatches(s.dom, date)
+	}
+
+	return anyDayItemMatches(s.dom, date) || anyDayItemMatches(s.dow, date)
+}
+
+func (s *extendedSchedule) describeDays() string {
+	var descriptions []string
+	for _, item := range append(append([]dayItem{}, s.dom...), s.dow...) {
+		descriptions = append(descriptions, item.description)
+	}
+
+	return strings.Join(descriptions, " or ")
+}
+
+func anyDayItemMatches(items []dayItem, date time.Time) bool {
+	for _, item := range items {
+		if item.matches(date) {
+			return true
+		}
+	}
+
+	return false
+}
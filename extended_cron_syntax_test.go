@@ -0,0 +1,144 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_ExtendedCronSyntax(t *testing.T) {
+	t.Run("next-activations", extendedNextActivationsTest)
+
+	t.Run("describe", extendedDescribeTest)
+
+	t.Run("malformed-expression", extendedMalformedTest)
+
+	t.Run("default-syntax", extendedDefaultSyntaxTest)
+}
+
+func extendedNextActivationsTest(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	expectations := map[string][]time.Time{
+		// Last day of the month
+		"0 0 0 L * ?": {
+			time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC),
+		},
+		// Third to last day of the month
+		"0 0 L-3 * ?": {
+			time.Date(2026, time.January, 28, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.February, 25, 0, 0, 0, 0, time.UTC),
+		},
+		// Last weekday of the month - 2026-01-31 is a Saturday
+		"0 30 18 LW * ?": {
+			time.Date(2026, time.January, 30, 18, 30, 0, 0, time.UTC),
+			time.Date(2026, time.February, 27, 18, 30, 0, 0, time.UTC),
+		},
+		// Weekday nearest to the 1st - 2026-08-01 is a Saturday
+		"0 0 1W 8 ?": {
+			time.Date(2026, time.August, 3, 0, 0, 0, 0, time.UTC),
+		},
+		// Second Tuesday
+		"0 0 ? * 2#2": {
+			time.Date(2026, time.January, 13, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC),
+		},
+		// Last Friday
+		"0 0 ? * FRIL": {
+			time.Date(2026, time.January, 30, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.February, 27, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for expression, expected := range expectations {
+		// given
+		schedule, err := crontask.ParseExtendedSchedule(expression)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", expression, err)
+		}
+
+		// when
+		preview := schedule.Preview(from, len(expected), time.UTC)
+
+		// then
+		if len(preview) != len(expected) {
+			t.Fatalf("expected %d activations for %q, got %d", len(expected), expression, len(preview))
+		}
+
+		for i := range expected {
+			if !preview[i].Equal(expected[i]) {
+				t.Errorf("expected activation %d of %q at %s, got %s", i, expression, expected[i], preview[i])
+			}
+		}
+	}
+}
+
+func extendedDescribeTest(t *testing.T) {
+	expectations := map[string]string{
+		"0 0 0 L * ?":    "At 00:00, on the last day of the month",
+		"0 30 18 LW * ?": "At 18:30, on the last weekday of the month",
+		"0 0 ? * 2#2":    "At 00:00, on the second Tuesday of the month",
+		"0 0 ? 1-3 5L":   "At 00:00, on the last Friday of the month, January through March",
+	}
+
+	for expression, expected := range expectations {
+		// given
+		schedule, err := crontask.ParseExtendedSchedule(expression)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", expression, err)
+		}
+
+		// when
+		description := schedule.Describe()
+
+		// then
+		if description != expected {
+			t.Errorf("expected description %q for %q, got %q", expected, expression, description)
+		}
+	}
+}
+
+func extendedMalformedTest(t *testing.T) {
+	for _, expression := range []string{"0 0 L-x * ?", "0 0 40W * ?", "0 0 ? * 2#6", "0 0 ? * 1-2L"} {
+		// when
+		_, err := crontask.ParseExtendedSchedule(expression)
+
+		// then
+		if err == nil {
+			t.Errorf("Expected error for %q, but none occurred", expression)
+		}
+	}
+}
+
+func extendedDefaultSyntaxTest(t *testing.T) {
+	// when
+	_, defaultErr := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("0 0 LW * ?"),
+	)
+
+	task, extendedErr := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("0 0 LW * ?"),
+		crontask.ExtendedCronSyntax(true),
+	)
+
+	// then
+	if defaultErr == nil {
+		t.Error("Expected error for extended syntax by default, but none occurred")
+	}
+
+	if extendedErr != nil {
+		t.Fatalf("unexpected error: %s", extendedErr)
+	}
+	defer task.Stop(context.Background())
+
+	if next := task.NextTime(); next.Day() < 26 {
+		t.Errorf("expected next time at the end of the month, got %s", next)
+	}
+}
@@ -0,0 +1,217 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/bsm/redislock"
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultHealthFailureThreshold is the default number of consecutive failed
+// elections, after which a synchronized cron task is considered not ready.
+const DefaultHealthFailureThreshold = 3
+
+var luaPing = redis.NewScript(`return redis.call("ping")`)
+
+// TaskHealth describes the health of a synchronized cron task on the running instance.
+type TaskHealth struct {
+	Name string `json:"name"`
+
+	// Stopped is true, if the task was stopped via Stop.
+	Stopped bool `json:"stopped"`
+
+	// BackendReachable is true, if redis answered the health check.
+	BackendReachable bool          `json:"backendReachable"`
+	BackendLatency   time.Duration `json:"backendLatency"`
+	BackendError     string        `json:"backendError,omitempty"`
+
+	// LastElection is the time of the last election, which was decided by
+	// redis - regardless of the instance it was won by. LastLeadership is
+	// the time of the last election won by the running instance.
+	LastElection   time.Time `json:"lastElection"`
+	LastLeadership time.Time `json:"lastLeadership"`
+
+	// ConsecutiveFailures is the number of elections in a row, which failed
+	// due to an error - e.g. as redis was unreachable, or the lock was lost
+	// after the election. LastFailure is the error of the latest of those.
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	LastFailure         string `json:"lastFailure,omitempty"`
+
+	// Live is true, unless the task was stopped. Ready is true, if the task
+	// is live, redis is reachable, and the number of consecutive failures is
+	// below the failure threshold.
+	Live  bool `json:"live"`
+	Ready bool `json:"ready"`
+}
+
+// HealthChecker is anything reporting the health of a task, such as SynchronizedCronTask.
+type HealthChecker interface {
+	Health(ctx context.Context) TaskHealth
+}
+
+// healthTracker keeps track of the outcome of elections.
+type healthTracker struct {
+	mutex sync.Mutex
+
+	failureThreshold int
+	clock            clock.Clock
+
+	lastElection        time.Time
+	lastLeadership      time.Time
+	consecutiveFailures int
+	lastFailure         string
+}
+
+// record records the outcome of a single firing.
+func (tracker *healthTracker) record(err error) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	now := tracker.clock.Now().UTC()
+
+	switch {
+	case errors.Is(err, ErrLockLost):
+		// The election was won, but the lock could not be renewed
+		tracker.lastElection, tracker.lastLeadership = now, now
+		tracker.consecutiveFailures++
+		tracker.lastFailure = err.Error()
+	case elected(err):
+		tracker.lastElection, tracker.lastLeadership = now, now
+		tracker.consecutiveFailures, tracker.lastFailure = 0, ""
+	case errors.Is(err, redislock.ErrNotObtained), errors.Is(err, errNotDue), errors.Is(err, errCompletionHeld),
+		errors.Is(err, errTriggerHandled):
+		tracker.lastElection = now
+		tracker.consecutiveFailures, tracker.lastFailure = 0, ""
+	case errors.Is(err, errPaused), errors.Is(err, ErrStopped), errors.Is(err, ErrLeadershipTimeout),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// No election took place - neither a success, nor a failure. A leadership
+		// timeout only ends up here, if it was reached before the election - e.g.
+		// while awaiting dependencies, or other instances finishing their shards.
+	default:
+		tracker.consecutiveFailures++
+		tracker.lastFailure = err.Error()
+	}
+}
+
+// Health returns the health of the task on the running instance. Redis is pinged
+// to determine its reachability, which is bound by the given context.
+func (synchronizedCronTask *SynchronizedCronTask) Health(ctx context.Context) TaskHealth {
+	tracker := synchronizedCronTask.health
+
+	tracker.mutex.Lock()
+	health := TaskHealth{
+		Name:                synchronizedCronTask.name,
+		Stopped:             synchronizedCronTask.shutdownCtx.Err() != nil,
+		LastElection:        tracker.lastElection,
+		LastLeadership:      tracker.lastLeadership,
+		ConsecutiveFailures: tracker.consecutiveFailures,
+		LastFailure:         tracker.lastFailure,
+	}
+	tracker.mutex.Unlock()
+
+	start := synchronizedCronTask.clock.Now()
+	if err := luaPing.Run(ctx, synchronizedCronTask.client, nil).Err(); err != nil {
+		health.BackendError = err.Error()
+	} else {
+		health.BackendReachable = true
+		health.BackendLatency = synchronizedCronTask.clock.Now().Sub(start)
+	}
+
+	health.Live = !health.Stopped
+	health.Ready = health.Live && health.BackendReachable && health.ConsecutiveFailures < tracker.failureThreshold
+
+	return health
+}
+
+// GroupHealth describes the aggregated health of several tasks.
+type GroupHealth struct {
+	// Live is true, if all tasks are live.
+	Live bool `json:"live"`
+
+	// Ready is true, if all tasks are ready.
+	Ready bool `json:"ready"`
+
+	Tasks []TaskHealth `json:"tasks"`
+}
+
+// HealthGroup aggregates the health of several tasks, e.g. all tasks of a
+// service. It provides http handlers for liveness and readiness probes.
+type HealthGroup struct {
+	checkers []HealthChecker
+	timeout  time.Duration
+}
+
+// NewHealthGroup creates a new HealthGroup of the given tasks. Every
+// health check of all tasks is bound by the given timeout.
+func NewHealthGroup(timeout time.Duration, checkers ...HealthChecker) *HealthGroup {
+	return &HealthGroup{
+		checkers: checkers,
+		timeout:  timeout,
+	}
+}
+
+// Health returns the aggregated health of all tasks, which are checked concurrently.
+func (group *HealthGroup) Health(ctx context.Context) GroupHealth {
+	ctx, cancel := context.WithTimeout(ctx, group.timeout)
+	defer cancel()
+
+	health := GroupHealth{
+		Live:  true,
+		Ready: true,
+		Tasks: make([]TaskHealth, len(group.checkers)),
+	}
+
+	wg := &sync.WaitGroup{}
+	for i, checker := range group.checkers {
+		wg.Add(1)
+		go func(i int, checker HealthChecker) {
+			defer wg.Done()
+			health.Tasks[i] = checker.Health(ctx)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, task := range health.Tasks {
+		health.Live = health.Live && task.Live
+		health.Ready = health.Ready && task.Ready
+	}
+
+	return health
+}
+
+// LivenessHandler returns an http handler, which responds with 200 OK if all
+// tasks are live, and 503 Service Unavailable otherwise. The aggregated health
+// is returned as JSON. Liveness does not depend on redis, as restarting an
+// instance does not resolve an unreachable redis.
+func (group *HealthGroup) LivenessHandler() http.Handler {
+	return group.handler(func(health GroupHealth) bool { return health.Live })
+}
+
+// ReadinessHandler returns an http handler, which responds with 200 OK if all
+// tasks are ready, and 503 Service Unavailable otherwise. The aggregated health
+// is returned as JSON.
+func (group *HealthGroup) ReadinessHandler() http.Handler {
+	return group.handler(func(health GroupHealth) bool { return health.Ready })
+}
+
+func (group *HealthGroup) handler(healthy func(health GroupHealth) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := group.Health(r.Context())
+
+		status := http.StatusOK
+		if !healthy(health) {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(health)
+	})
+}
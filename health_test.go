@@ -0,0 +1,145 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Health(t *testing.T) {
+	t.Run("unreachable-backend", unreachableBackendHealthTest)
+
+	t.Run("group", healthGroupTest)
+
+	t.Run("handlers", healthHandlersTest)
+}
+
+func unreachableBackendHealthTest(t *testing.T) {
+	// given
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+
+	task, err := crontask.NewSynchronizedCronTask(
+		client,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("0 0 0 1 1 *"),
+		crontask.HealthFailureThreshold(2),
+		crontask.Logger(nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// when
+	task.ExecuteNow()
+	first := task.Health(context.Background())
+
+	task.ExecuteNow()
+	second := task.Health(context.Background())
+
+	task.Stop(context.Background())
+	stopped := task.Health(context.Background())
+
+	// then
+	if first.BackendReachable || first.BackendError == "" {
+		t.Errorf("expected backend to be unreachable, got %+v", first)
+	}
+
+	if first.ConsecutiveFailures != 1 || first.LastFailure == "" || !first.LastElection.IsZero() {
+		t.Errorf("expected one failed election, got %+v", first)
+	}
+
+	if !first.Live || first.Ready {
+		t.Errorf("expected task to be live but not ready, got %+v", first)
+	}
+
+	if second.ConsecutiveFailures != 2 {
+		t.Errorf("expected two consecutive failures, got %d", second.ConsecutiveFailures)
+	}
+
+	if !stopped.Stopped || stopped.Live {
+		t.Errorf("expected stopped task not to be live, got %+v", stopped)
+	}
+}
+
+// fakeHealthChecker reports a fixed health.
+type fakeHealthChecker crontask.TaskHealth
+
+func (checker fakeHealthChecker) Health(ctx context.Context) crontask.TaskHealth {
+	return crontask.TaskHealth(checker)
+}
+
+func healthGroupTest(t *testing.T) {
+	// given
+	group := crontask.NewHealthGroup(
+		time.Second,
+		fakeHealthChecker{Name: "ready", Live: true, Ready: true},
+		fakeHealthChecker{Name: "not-ready", Live: true, Ready: false},
+	)
+
+	// when
+	health := group.Health(context.Background())
+
+	// then
+	if !health.Live || health.Ready {
+		t.Errorf("expected group to be live but not ready, got %+v", health)
+	}
+
+	if len(health.Tasks) != 2 || health.Tasks[0].Name != "ready" || health.Tasks[1].Name != "not-ready" {
+		t.Errorf("expected health of all tasks in order, got %+v", health.Tasks)
+	}
+}
+
+func healthHandlersTest(t *testing.T) {
+	testCases := []struct {
+		name      string
+		checker   fakeHealthChecker
+		liveness  int
+		readiness int
+	}{
+		{name: "ready", checker: fakeHealthChecker{Live: true, Ready: true}, liveness: http.StatusOK, readiness: http.StatusOK},
+		{name: "not-ready", checker: fakeHealthChecker{Live: true}, liveness: http.StatusOK, readiness: http.StatusServiceUnavailable},
+		{name: "stopped", checker: fakeHealthChecker{Stopped: true}, liveness: http.StatusServiceUnavailable, readiness: http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			group := crontask.NewHealthGroup(time.Second, testCase.checker)
+
+			// when
+			liveness := httptest.NewRecorder()
+			group.LivenessHandler().ServeHTTP(liveness, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+			readiness := httptest.NewRecorder()
+			group.ReadinessHandler().ServeHTTP(readiness, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			// then
+			if liveness.Code != testCase.liveness {
+				t.Errorf("expected liveness status %d, got %d", testCase.liveness, liveness.Code)
+			}
+
+			if readiness.Code != testCase.readiness {
+				t.Errorf("expected readiness status %d, got %d", testCase.readiness, readiness.Code)
+			}
+
+			health := crontask.GroupHealth{}
+			if err := json.Unmarshal(readiness.Body.Bytes(), &health); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(health.Tasks) != 1 {
+				t.Errorf("expected health of task in body, got %s", readiness.Body.String())
+			}
+		})
+	}
+}
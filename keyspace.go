@@ -0,0 +1,37 @@
+package crontask
+
+import (
+	"fmt"
+)
+
+// Keyspace describes how the redis keys - and pub/sub channels - of synchronized
+// cron tasks, singletons and registries are built. The zero value builds keys of
+// the form "<name>.<kind>", e.g. "some-task.lock".
+type Keyspace struct {
+	// Namespace prefixes every key, so several applications can share a
+	// redis without colliding - e.g. if both use crontask.DefaultName.
+	Namespace string
+
+	// HashTags wraps the name of a task into a redis cluster hash tag,
+	// e.g. "{some-task}.lock". Thus, all keys of a task are assigned the
+	// same slot of a redis cluster.
+	HashTags bool
+}
+
+// key builds the redis key of a given kind for the task with the given name.
+func (keyspace Keyspace) key(name string, kind string) string {
+	if keyspace.HashTags {
+		name = fmt.Sprintf("{%s}", name)
+	}
+
+	return keyspace.prefix(fmt.Sprintf("%s.%s", name, kind))
+}
+
+// prefix prefixes the given key with the namespace - if any.
+func (keyspace Keyspace) prefix(key string) string {
+	if keyspace.Namespace == "" {
+		return key
+	}
+
+	return fmt.Sprintf("%s.%s", keyspace.Namespace, key)
+}
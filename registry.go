@@ -0,0 +1,462 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/bsm/redislock"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Registrations are prefixed with their expiry, as per the clock of the
+	// redis server - so clocks of instances never have to be compared.
+	luaRegisterInstance = redis.NewScript(`
+		redis.replicate_commands()
+		local now = redis.call("time")
+		local expiresAt = now[1] * 1000 + math.floor(now[2] / 1000) + tonumber(ARGV[3])
+		return redis.call("hset", KEYS[1], ARGV[1], string.format("%.0f", expiresAt) .. ":" .. ARGV[2])
+	`)
+	luaDeregisterInstance = redis.NewScript(`return redis.call("hdel", KEYS[1], unpack(ARGV))`)
+	luaListInstances      = redis.NewScript(`
+		local now = redis.call("time")
+		local result = redis.call("hgetall", KEYS[1])
+		table.insert(result, 1, string.format("%.0f", now[1] * 1000 + math.floor(now[2] / 1000)))
+		return result
+	`)
+	luaLockHolder  = redis.NewScript(`return redis.call("get", KEYS[1]) or ""`)
+	luaSetHolder   = redis.NewScript(`return redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])`)
+	luaRenewHolder = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("pexpire", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	luaClearHolder = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		end
+		return 0
+	`)
+)
+
+const (
+	// DefaultRegistryKey is the default redis key for the hash used
+	// to track all instances registered in a registry.
+	DefaultRegistryKey = "crontask.instances"
+
+	// DefaultRegistryHeartbeat is the default interval, in which an
+	// instance renews its registration.
+	DefaultRegistryHeartbeat = 5 * time.Second
+
+	// DefaultRegistryTTL is the default time after which an instance
+	// is considered dead, if it did not renew its registration.
+	DefaultRegistryTTL = 15 * time.Second
+)
+
+// Instance describes a single running process, which participates in
+// elections of synchronized cron tasks and singletons.
+type Instance struct {
+	ID        string    `json:"id"`
+	Hostname  string    `json:"hostname"`
+	Version   string    `json:"version"`
+	StartTime time.Time `json:"startTime"`
+	Tasks     []string  `json:"tasks"`
+
+	// ExpiresAt is the time the registration expires, as per the clock of
+	// the redis server. It is set upon listing instances.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Registry registers the running instance in redis, and periodically renews
+// the registration. It allows listing all live instances, and which instance
+// currently holds the lock of each task.
+//
+// Tasks and singletons are added to the registry via the InstanceRegistry option.
+//
+// It supports graceful shutdowns via its Stop() function.
+type Registry struct {
+	client   redislock.RedisClient
+	key      string
+	keyspace Keyspace
+
+	instance      Instance
+	instanceMutex sync.Mutex
+
+	heartbeat time.Duration
+	ttl       time.Duration
+	clock     clock.Clock
+
+	logger *logrus.Logger
+
+	shutdownFunc func()
+	done         chan struct{}
+}
+
+// NewRegistryWithOptions creates a new Registry instance, which immediately
+// starts to register the running instance. Invalid options are reported by a
+// single *ValidationError.
+func NewRegistryWithOptions(client redislock.RedisClient, options *RegistryOptions) (*Registry, error) {
+	if options.Logger == nil {
+		// Create a "noop" logger, so we don't have to check for
+		// the logger being nil
+		logger := logrus.New()
+		logger.Out = io.Discard
+
+		options.Logger = logger
+	}
+
+	if options.Clock == nil {
+		options.Clock = clock.Real()
+	}
+
+	if err := options.validateRegistry(); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.InstanceID == "" {
+		id, err := generateInstanceID(hostname)
+		if err != nil {
+			return nil, err
+		}
+
+		options.InstanceID = id
+	}
+
+	shutdownCtx, shutdownFunc := context.WithCancel(context.Background())
+
+	registry := &Registry{
+		client:   client,
+		key:      options.Keyspace.prefix(options.Key),
+		keyspace: options.Keyspace,
+
+		instance: Instance{
+			ID:        options.InstanceID,
+			Hostname:  hostname,
+			Version:   options.Version,
+			StartTime: options.Clock.Now().UTC(),
+			Tasks:     []string{},
+		},
+
+		heartbeat: options.Heartbeat,
+		ttl:       options.TTL,
+		clock:     options.Clock,
+
+		logger: options.Logger,
+
+		shutdownFunc: shutdownFunc,
+		done:         make(chan struct{}),
+	}
+
+	go registry.renew(shutdownCtx)
+
+	return registry, nil
+}
+
+// NewRegistry creates a new Registry instance, which immediately starts to
+// register the running instance.
+func NewRegistry(client redislock.RedisClient, setters ...RegistryOption) (*Registry, error) {
+	// Default Options
+	args := &RegistryOptions{
+		Key: DefaultRegistryKey,
+
+		Logger: logrus.StandardLogger(),
+
+		Heartbeat: DefaultRegistryHeartbeat,
+		TTL:       DefaultRegistryTTL,
+	}
+
+	for _, setter := range setters {
+		setter(args)
+	}
+
+	return NewRegistryWithOptions(client, args)
+}
+
+// InstanceID returns the id of the running instance.
+func (registry *Registry) InstanceID() string {
+	return registry.instance.ID
+}
+
+// Stop gracefully stops the registry, and removes the registration
+// of the running instance.
+func (registry *Registry) Stop(ctx context.Context) {
+	registry.shutdownFunc()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-registry.done:
+	}
+
+	if err := luaDeregisterInstance.Run(ctx, registry.client, []string{registry.key}, registry.instance.ID).Err(); err != nil {
+		registry.logger.Warnf("Failed to deregister instance %q: %s", registry.instance.ID, err)
+	}
+}
+
+// Instances returns all live instances, sorted by their id. Instances whose
+// registration expired are removed from redis along the way.
+func (registry *Registry) Instances(ctx context.Context) ([]Instance, error) {
+	instances, expired, err := listInstances(ctx, registry.client, registry.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expired) > 0 {
+		if err := luaDeregisterInstance.Run(ctx, registry.client, []string{registry.key}, expired...).Err(); err != nil {
+			registry.logger.Warnf("Failed to remove expired instances: %s", err)
+		}
+	}
+
+	return instances, nil
+}
+
+// ListInstances returns all live instances registered in the registry with the
+// given key, sorted by their id. Unlike Registry.Instances, it neither requires
+// a running registry, nor does it modify the registry.
+//
+// Registries within a custom keyspace must be listed via Keyspace.ListInstances.
+func ListInstances(ctx context.Context, client redislock.RedisClient, key string) ([]Instance, error) {
+	return Keyspace{}.ListInstances(ctx, client, key)
+}
+
+// ListInstances returns all live instances registered in the registry with the
+// given key within the keyspace. See crontask.ListInstances for details.
+func (keyspace Keyspace) ListInstances(ctx context.Context, client redislock.RedisClient, key string) ([]Instance, error) {
+	instances, _, err := listInstances(ctx, client, keyspace.prefix(key))
+	return instances, err
+}
+
+// listInstances returns all live instances sorted by their id, and the ids of all expired instances.
+func listInstances(ctx context.Context, client redislock.RedisClient, key string) ([]Instance, []interface{}, error) {
+	res, err := luaListInstances.Run(ctx, client, []string{key}).StringSlice()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil, errors.New("unexpected empty reply while listing instances")
+	}
+
+	now, err := strconv.ParseInt(res[0], 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instances := make([]Instance, 0, len(res)/2)
+	var expired []interface{}
+	for i := 1; i+1 < len(res); i += 2 {
+		expiresAt, data, ok := strings.Cut(res[i+1], ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("malformed registration of instance %q", res[i])
+		}
+
+		millis, err := strconv.ParseInt(expiresAt, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if millis <= now {
+			expired = append(expired, res[i])
+			continue
+		}
+
+		instance := Instance{}
+		if err := json.Unmarshal([]byte(data), &instance); err != nil {
+			return nil, nil, err
+		}
+
+		instance.ExpiresAt = time.UnixMilli(millis).UTC()
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+
+	return instances, expired, nil
+}
+
+// LockHolders returns the id of the instance currently holding the lock, for
+// each task of all live instances. Tasks whose lock is currently not held map
+// to an empty string.
+func (registry *Registry) LockHolders(ctx context.Context) (map[string]string, error) {
+	instances, err := registry.Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make(map[string]string)
+	for _, instance := range instances {
+		for _, task := range instance.Tasks {
+			if _, ok := holders[task]; ok {
+				continue
+			}
+
+			holder, err := registry.keyspace.LockHolder(ctx, registry.client, task)
+			if err != nil {
+				return nil, err
+			}
+
+			holders[task] = holder
+		}
+	}
+
+	return holders, nil
+}
+
+// LockHolder returns the id of the instance currently holding the lock of the
+// task with the given name. If the lock is not held, or the holding instance
+// is not part of a registry, an empty string is returned. The holder is recorded
+// next to the lock, so it might lag behind the lock by one redis round trip.
+//
+// Locks within a custom keyspace must be looked up via Keyspace.LockHolder.
+func LockHolder(ctx context.Context, client redislock.RedisClient, name string) (string, error) {
+	return Keyspace{}.LockHolder(ctx, client, name)
+}
+
+// LockHolder returns the id of the instance currently holding the lock of the task
+// with the given name within the keyspace. See crontask.LockHolder for details.
+func (keyspace Keyspace) LockHolder(ctx context.Context, client redislock.RedisClient, name string) (string, error) {
+	return luaLockHolder.Run(ctx, client, []string{holderKey(keyspace.key(name, "lock"))}).Text()
+}
+
+// holderKey returns the key, which records the id of the instance holding the lock with the given key.
+func holderKey(lockKey string) string {
+	return fmt.Sprintf("%s.holder", lockKey)
+}
+
+// recordHolder records the running instance as the holder of the given lock,
+// if the elector is part of a registry.
+func (elector *elector) recordHolder(ctx context.Context, lock *redislock.Lock, lockTimeout time.Duration) {
+	if elector.registry == nil {
+		return
+	}
+
+	if err := luaSetHolder.Run(ctx, elector.client, []string{holderKey(lock.Key())}, elector.registry.InstanceID(), lockTimeout.Milliseconds()).Err(); err != nil {
+		elector.logger.Warnf("Failed to record holder of lock %q: %s", lock.Key(), err)
+	}
+}
+
+// renewHolder extends the record of the running instance as the holder of the
+// given lock, if the elector is part of a registry.
+func (elector *elector) renewHolder(ctx context.Context, lock *redislock.Lock, lockTimeout time.Duration) {
+	if elector.registry == nil {
+		return
+	}
+
+	if err := luaRenewHolder.Run(ctx, elector.client, []string{holderKey(lock.Key())}, elector.registry.InstanceID(), lockTimeout.Milliseconds()).Err(); err != nil {
+		elector.logger.Warnf("Failed to renew holder of lock %q: %s", lock.Key(), err)
+	}
+}
+
+// clearHolder removes the record of the running instance as the holder of the
+// given lock, if the elector is part of a registry.
+func (elector *elector) clearHolder(ctx context.Context, lock *redislock.Lock) {
+	if elector.registry == nil {
+		return
+	}
+
+	if err := luaClearHolder.Run(ctx, elector.client, []string{holderKey(lock.Key())}, elector.registry.InstanceID()).Err(); err != nil {
+		elector.logger.Warnf("Failed to clear holder of lock %q: %s", lock.Key(), err)
+	}
+}
+
+// addTask adds the name of a task to the registration of the running instance.
+func (registry *Registry) addTask(name string) {
+	registry.instanceMutex.Lock()
+	defer registry.instanceMutex.Unlock()
+
+	for _, task := range registry.instance.Tasks {
+		if task == name {
+			return
+		}
+	}
+
+	registry.instance.Tasks = append(registry.instance.Tasks, name)
+}
+
+// removeTask removes the name of a task from the registration of the running instance.
+func (registry *Registry) removeTask(name string) {
+	registry.instanceMutex.Lock()
+	defer registry.instanceMutex.Unlock()
+
+	for i, task := range registry.instance.Tasks {
+		if task == name {
+			registry.instance.Tasks = append(registry.instance.Tasks[:i], registry.instance.Tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+// renew periodically renews the registration of the running instance, until the context is canceled.
+func (registry *Registry) renew(ctx context.Context) {
+	defer close(registry.done)
+
+	ticker := registry.clock.NewTicker(registry.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		if err := registry.register(ctx); err != nil && ctx.Err() == nil {
+			registry.logger.Errorf("Failed to renew registration of instance %q: %s", registry.instance.ID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+func (registry *Registry) register(ctx context.Context) error {
+	registry.instanceMutex.Lock()
+	instance := registry.instance
+	instance.Tasks = append([]string{}, registry.instance.Tasks...)
+	registry.instanceMutex.Unlock()
+
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+
+	return luaRegisterInstance.Run(ctx, registry.client, []string{registry.key}, instance.ID, data, registry.ttl.Milliseconds()).Err()
+}
+
+func generateInstanceID(hostname string) (string, error) {
+	suffix, err := randomHex(4)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s", hostname, suffix), nil
+}
+
+// randomHex returns a random hex string of the given length in bytes.
+func randomHex(length int) (string, error) {
+	data := make([]byte, length)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
@@ -0,0 +1,101 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/sirupsen/logrus"
+
+	"time"
+)
+
+// RegistryOptions bundles all available configuration
+// properties for an instance registry.
+type RegistryOptions struct {
+	Key      string
+	Keyspace Keyspace
+
+	InstanceID string
+	Version    string
+
+	Logger *logrus.Logger
+
+	Heartbeat time.Duration
+	TTL       time.Duration
+
+	Clock clock.Clock
+}
+
+// RegistryOption represents an option for an instance registry.
+type RegistryOption func(*RegistryOptions)
+
+// RegistryKey sets the redis key for the hash used to track
+// all instances registered in the registry.
+// The default is crontask.DefaultRegistryKey.
+func RegistryKey(key string) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Key = key
+	}
+}
+
+// RegistryKeyspace sets the keyspace of the registry. Its namespace prefixes the
+// registry key, and it is used to look up the lock holders of all tasks. Thus, it
+// should match the keyspace of the tasks added to the registry.
+// The default is the zero Keyspace, which uses no namespace and no hash tags.
+func RegistryKeyspace(keyspace Keyspace) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Keyspace = keyspace
+	}
+}
+
+// RegistryInstanceID sets the id of the running instance.
+// The default is the hostname, suffixed with a random string.
+func RegistryInstanceID(instanceID string) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.InstanceID = instanceID
+	}
+}
+
+// RegistryVersion sets the version of the running instance,
+// e.g. the version of the application.
+// The default is an empty string.
+func RegistryVersion(version string) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Version = version
+	}
+}
+
+// RegistryLogger sets the logger of the registry.
+// The default is the logrus global default logger.
+func RegistryLogger(logger *logrus.Logger) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Logger = logger
+	}
+}
+
+// RegistryHeartbeat sets the interval, in which the running instance
+// renews its registration. This should be smaller than the RegistryTTL.
+// The default is crontask.DefaultRegistryHeartbeat.
+func RegistryHeartbeat(heartbeat time.Duration) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Heartbeat = heartbeat
+	}
+}
+
+// RegistryTTL sets the time after which an instance is considered
+// dead, if it did not renew its registration.
+// The default is crontask.DefaultRegistryTTL.
+func RegistryTTL(ttl time.Duration) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.TTL = ttl
+	}
+}
+
+// RegistryClock sets the clock, which the start time of the running instance
+// is taken from, and its registration is renewed on. Registrations expire as
+// per the clock of the redis server, regardless of this clock.
+// The default is nil, which uses the real clock.
+func RegistryClock(registryClock clock.Clock) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.Clock = registryClock
+	}
+}
@@ -0,0 +1,133 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/sirupsen/logrus"
+
+	"testing"
+	"time"
+)
+
+// Tests that the RegistryKey option correctly applies.
+func Test_RegistryOption_RegistryKey(t *testing.T) {
+	// given
+	option := crontask.RegistryKey("bar")
+	options := &crontask.RegistryOptions{Key: "foo"}
+
+	// when
+	option(options)
+
+	// then
+	if options.Key != "bar" {
+		t.Errorf("key not correctly applied, got %s", options.Key)
+	}
+}
+
+// Tests that the RegistryKeyspace option correctly applies.
+func Test_RegistryOption_RegistryKeyspace(t *testing.T) {
+	// given
+	expected := crontask.Keyspace{Namespace: "bar", HashTags: true}
+	option := crontask.RegistryKeyspace(expected)
+	options := &crontask.RegistryOptions{Keyspace: crontask.Keyspace{Namespace: "foo"}}
+
+	// when
+	option(options)
+
+	// then
+	if options.Keyspace != expected {
+		t.Errorf("keyspace not correctly applied, got %v", options.Keyspace)
+	}
+}
+
+// Tests that the RegistryInstanceID option correctly applies.
+func Test_RegistryOption_RegistryInstanceID(t *testing.T) {
+	// given
+	option := crontask.RegistryInstanceID("bar")
+	options := &crontask.RegistryOptions{InstanceID: "foo"}
+
+	// when
+	option(options)
+
+	// then
+	if options.InstanceID != "bar" {
+		t.Errorf("instance id not correctly applied, got %s", options.InstanceID)
+	}
+}
+
+// Tests that the RegistryVersion option correctly applies.
+func Test_RegistryOption_RegistryVersion(t *testing.T) {
+	// given
+	option := crontask.RegistryVersion("bar")
+	options := &crontask.RegistryOptions{Version: "foo"}
+
+	// when
+	option(options)
+
+	// then
+	if options.Version != "bar" {
+		t.Errorf("version not correctly applied, got %s", options.Version)
+	}
+}
+
+// Tests that the RegistryLogger option correctly applies.
+func Test_RegistryOption_RegistryLogger(t *testing.T) {
+	// given
+	option := crontask.RegistryLogger(&logrus.Logger{})
+	options := &crontask.RegistryOptions{Logger: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Logger == nil {
+		t.Error("logger not correctly applied, got nil")
+	}
+}
+
+// Tests that the RegistryHeartbeat option correctly applies.
+func Test_RegistryOption_RegistryHeartbeat(t *testing.T) {
+	// given
+	option := crontask.RegistryHeartbeat(time.Second)
+	options := &crontask.RegistryOptions{Heartbeat: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.Heartbeat != time.Second {
+		t.Errorf("heartbeat not correctly applied, got %s", options.Heartbeat)
+	}
+}
+
+// Tests that the RegistryTTL option correctly applies.
+func Test_RegistryOption_RegistryTTL(t *testing.T) {
+	// given
+	option := crontask.RegistryTTL(time.Second)
+	options := &crontask.RegistryOptions{TTL: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.TTL != time.Second {
+		t.Errorf("ttl not correctly applied, got %s", options.TTL)
+	}
+}
+
+// Tests that the RegistryClock option correctly applies.
+func Test_RegistryOption_RegistryClock(t *testing.T) {
+	// given
+	fakeClock := clock.NewFake(time.Now())
+	option := crontask.RegistryClock(fakeClock)
+	options := &crontask.RegistryOptions{Clock: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Clock != fakeClock {
+		t.Errorf("clock not correctly applied, got %v", options.Clock)
+	}
+}
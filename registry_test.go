@@ -0,0 +1,190 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_Registry(t *testing.T) {
+	t.Run("invalid-heartbeat", invalidRegistryHeartbeatTest)
+	t.Run("invalid-ttl", invalidRegistryTTLTest)
+
+	redisVersions := []string{
+		"5-alpine",
+		"6-alpine",
+		"7-alpine",
+	}
+	for i := range redisVersions {
+		version := redisVersions[i]
+
+		t.Run(fmt.Sprintf("redis:%s", version), func(t *testing.T) {
+			t.Parallel()
+
+			t.Run("registry-test", registryTest(version))
+			t.Run("registry-expiry-test", registryExpiryTest(version))
+		})
+	}
+}
+
+func invalidRegistryHeartbeatTest(t *testing.T) {
+	for _, heartbeat := range []time.Duration{0, -time.Second} {
+		// when
+		registry, err := crontask.NewRegistry(nil, crontask.RegistryHeartbeat(heartbeat))
+
+		// then
+		assertInvalidRegistryOption(t, registry, err, "Heartbeat")
+	}
+}
+
+func invalidRegistryTTLTest(t *testing.T) {
+	for _, ttl := range []time.Duration{-time.Second, crontask.DefaultRegistryHeartbeat} {
+		// when
+		registry, err := crontask.NewRegistry(nil, crontask.RegistryTTL(ttl))
+
+		// then
+		assertInvalidRegistryOption(t, registry, err, "TTL")
+	}
+}
+
+func assertInvalidRegistryOption(t *testing.T, registry *crontask.Registry, err error, option string) {
+	t.Helper()
+
+	var validationErr *crontask.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	if registry != nil {
+		t.Error("expected no registry being returned, but was")
+	}
+
+	if len(validationErr.Errors) != 1 || validationErr.Errors[0].Option != option {
+		t.Errorf("expected only option %s to be reported as invalid, got %v", option, validationErr.Errors)
+	}
+}
+
+func registryTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		registry, err := crontask.NewRegistry(
+			client,
+			crontask.RegistryInstanceID("some-instance"),
+			crontask.RegistryVersion("1.0.0"),
+			crontask.RegistryHeartbeat(10*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer registry.Stop(context.Background())
+
+		release := make(chan struct{})
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				<-release
+				return nil
+			},
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.InstanceRegistry(registry),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		go task.ExecuteNow()
+		defer close(release)
+
+		// Wait for the registration to be renewed, and the task to be executing
+		time.Sleep(100 * time.Millisecond)
+
+		instances, err := registry.Instances(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		holders, err := registry.LockHolders(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		listed, err := crontask.ListInstances(context.Background(), client, crontask.DefaultRegistryKey)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// then
+		if len(instances) != 1 {
+			t.Fatalf("expected exactly one instance, but got %d", len(instances))
+		}
+
+		if instance := instances[0]; instance.ID != "some-instance" || instance.Version != "1.0.0" ||
+			len(instance.Tasks) != 1 || instance.Tasks[0] != task.Name() {
+			t.Errorf("unexpected instance %v", instance)
+		}
+
+		if len(listed) != 1 || listed[0].ID != "some-instance" {
+			t.Errorf("expected listed instances to match the instances of the registry, but got %v", listed)
+		}
+
+		if holder := holders[task.Name()]; holder != "some-instance" {
+			t.Errorf("expected lock holder %q, but got %q", "some-instance", holder)
+		}
+	}
+}
+
+func registryExpiryTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		live, err := crontask.NewRegistry(
+			client,
+			crontask.RegistryInstanceID("live-instance"),
+			crontask.RegistryHeartbeat(10*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer live.Stop(context.Background())
+
+		// Registers once, and never renews its registration in time
+		dead, err := crontask.NewRegistry(
+			client,
+			crontask.RegistryInstanceID("dead-instance"),
+			crontask.RegistryHeartbeat(time.Hour),
+			crontask.RegistryTTL(50*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer dead.Stop(context.Background())
+
+		// when
+		time.Sleep(100 * time.Millisecond)
+
+		instances, err := live.Instances(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// then
+		if len(instances) != 1 || instances[0].ID != "live-instance" {
+			t.Fatalf("expected only the live instance, but got %v", instances)
+		}
+
+		if instances[0].ExpiresAt.IsZero() {
+			t.Errorf("expected the expiry of the live instance to be set")
+		}
+	}
+}
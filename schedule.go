@@ -0,0 +1,237 @@
+package crontask
+
+import (
+	"github.com/robfig/cron/v3"
+
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errNotDue signals that the interval of an interval schedule has not yet
+// passed, as another instance finished a run in the meantime.
+var errNotDue = errors.New("interval not yet passed")
+
+// Schedule describes the activations of a synchronized cron task. Every
+// cron.Schedule of robfig/cron is a Schedule, and vice versa.
+type Schedule interface {
+	// Next returns the next activation time, later than the given time.
+	// If the zero time is returned, the schedule never activates again.
+	Next(t time.Time) time.Time
+}
+
+// parseSchedule builds the schedule of the given options. Unless a schedule is set,
+// the cron expressions are parsed - of which the union forms the schedule.
+func parseSchedule(options *TaskOptions) (cron.Schedule, error) {
+	if options.Schedule != nil {
+		return options.Schedule, nil
+	}
+
+	parse := cronParser.Parse
+	if options.ExtendedSyntax {
+		parse = parseExtendedCronExpression
+	}
+
+	if len(options.CronExpressions) == 0 {
+		return parse(options.CronExpression)
+	}
+
+	schedules := make(unionSchedule, len(options.CronExpressions))
+	for i, cronExpression := range options.CronExpressions {
+		schedule, err := parse(cronExpression)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", cronExpression, err)
+		}
+
+		schedules[i] = schedule
+	}
+
+	if len(schedules) == 1 {
+		return schedules[0], nil
+	}
+
+	return schedules, nil
+}
+
+// unionSchedule is a schedule, which activates whenever any of its schedules
+// activates. Coincident activations of several schedules activate only once.
+type unionSchedule []cron.Schedule
+
+func (s unionSchedule) Next(t time.Time) time.Time {
+	var earliest time.Time
+	for _, schedule := range s {
+		next := schedule.Next(t)
+		if next.IsZero() {
+			continue
+		}
+
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+
+	return earliest
+}
+
+// At returns a schedule, which activates exactly once at the given time.
+// If the time already passed when the task is created, it never activates.
+func At(t time.Time) Schedule {
+	return atSchedule{at: t}
+}
+
+type atSchedule struct {
+	at time.Time
+}
+
+func (s atSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+
+	return time.Time{}
+}
+
+// After returns a schedule, which activates exactly once after the given
+// delay. The delay is measured from the creation of the task.
+func After(delay time.Duration) Schedule {
+	return &afterSchedule{delay: delay}
+}
+
+type afterSchedule struct {
+	delay time.Duration
+
+	once sync.Once
+	at   time.Time
+}
+
+func (s *afterSchedule) Next(t time.Time) time.Time {
+	// The schedule is asked for its first activation - as per
+	// the clock of the task - when the task starts
+	s.once.Do(func() {
+		s.at = t.Add(s.delay)
+	})
+
+	return atSchedule{at: s.at}.Next(t)
+}
+
+// Interval returns a schedule, which activates after the given interval has
+// passed since the end of the last run of the task - across all instances.
+// The end of every run is recorded in redis, and each activation waits for
+// the interval to pass since the latest recorded end. If no run is recorded,
+// the first run starts one interval after the task was created.
+//
+// An interval schedule cannot be combined with shards.
+func Interval(interval time.Duration) Schedule {
+	return &intervalSchedule{interval: interval}
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+
+	dueMutex sync.Mutex
+	due      time.Time
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	s.dueMutex.Lock()
+	defer s.dueMutex.Unlock()
+
+	// The due time is only known after a run finished. Until then, activate
+	// one interval later, and wait for the actual due time upon activation.
+	if s.due.After(t) {
+		return s.due
+	}
+
+	return t.Add(s.interval)
+}
+
+func (s *intervalSchedule) setDue(due time.Time) {
+	s.dueMutex.Lock()
+	defer s.dueMutex.Unlock()
+
+	s.due = due
+}
+
+// awaitInterval blocks until the interval has passed since the end of the
+// last run of any instance, and returns that point in time.
+func (synchronizedCronTask *SynchronizedCronTask) awaitInterval(ctx context.Context) (time.Time, error) {
+	due, err := synchronizedCronTask.intervalDue(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	wait := due.Sub(synchronizedCronTask.clock.Now())
+	if wait <= 0 {
+		return due, nil
+	}
+
+	synchronizedCronTask.logger.Tracef("Synchronized task %q is waiting %s for its interval to pass", synchronizedCronTask.name, wait)
+
+	timer := synchronizedCronTask.clock.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return time.Time{}, context.Cause(ctx)
+	case <-timer.C():
+		return due, nil
+	}
+}
+
+// intervalDue returns the time the interval passes since the end of the
+// last run of any instance. If no run is recorded, the current time is returned.
+func (synchronizedCronTask *SynchronizedCronTask) intervalDue(ctx context.Context) (time.Time, error) {
+	finished, err := synchronizedCronTask.timestamp(ctx, synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "finished"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if finished.IsZero() {
+		return synchronizedCronTask.clock.Now().UTC(), nil
+	}
+
+	due := finished.Add(synchronizedCronTask.interval.interval)
+	synchronizedCronTask.interval.setDue(due)
+
+	return due, nil
+}
+
+// wrapIntervalFunc wraps a task function, so that the end of the run is recorded
+// in redis while the lock is still held. Unless the run is manual, it is skipped
+// if another instance finished a run right before the lock was obtained.
+func (synchronizedCronTask *SynchronizedCronTask) wrapIntervalFunc(manual bool, taskFunc TaskFunc) TaskFunc {
+	return func(ctx context.Context, task Task) error {
+		if !manual {
+			due, err := synchronizedCronTask.intervalDue(ctx)
+			if err != nil {
+				return err
+			}
+
+			if due.After(synchronizedCronTask.clock.Now()) {
+				return electionError{errNotDue}
+			}
+		}
+
+		taskErr := taskFunc(ctx, task)
+
+		finished := synchronizedCronTask.clock.Now().UTC()
+		synchronizedCronTask.interval.setDue(finished.Add(synchronizedCronTask.interval.interval))
+
+		if err := luaMarkCompleted.Run(
+			ctx, synchronizedCronTask.client,
+			[]string{synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "finished")},
+			finished.UnixMilli(),
+		).Err(); err != nil {
+			// If there was an task error, give that precedence over the redis error
+			if taskErr != nil {
+				return taskErr
+			}
+
+			return err
+		}
+
+		return taskErr
+	}
+}
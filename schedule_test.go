@@ -0,0 +1,120 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_Schedule(t *testing.T) {
+	t.Run("at", atScheduleTest)
+
+	t.Run("after", afterScheduleTest)
+
+	t.Run("after-with-fake-clock", afterScheduleWithFakeClockTest)
+
+	t.Run("interval", intervalScheduleTest)
+
+	t.Run("interval-with-shards", intervalWithShardsTest)
+}
+
+func atScheduleTest(t *testing.T) {
+	// given
+	at := time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC)
+
+	// when
+	schedule := crontask.At(at)
+
+	// then
+	if next := schedule.Next(at.Add(-time.Hour)); !next.Equal(at) {
+		t.Errorf("expected next activation at %s, got %s", at, next)
+	}
+
+	if next := schedule.Next(at); !next.IsZero() {
+		t.Errorf("expected no further activation, got %s", next)
+	}
+}
+
+func afterScheduleTest(t *testing.T) {
+	// given
+	start := time.Now()
+
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.TaskSchedule(crontask.After(30*time.Minute)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while creating task: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	// then
+	next := task.NextTime()
+	if next.Before(start.Add(30*time.Minute)) || next.After(time.Now().Add(30*time.Minute)) {
+		t.Errorf("expected next activation 30 minutes after startup, got %s", next)
+	}
+
+	if again := task.NextTime(); !again.Equal(next) {
+		t.Errorf("expected stable next activation %s, got %s", next, again)
+	}
+}
+
+func afterScheduleWithFakeClockTest(t *testing.T) {
+	// given
+	start := time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFake(start)
+
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.TaskSchedule(crontask.After(30*time.Minute)),
+		crontask.Clock(fakeClock),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while creating task: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	// then
+	if next := task.NextTime(); !next.Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("expected next activation 30 minutes after the fake startup, got %s", next)
+	}
+}
+
+func intervalScheduleTest(t *testing.T) {
+	// given
+	now := time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC)
+
+	// when
+	schedule := crontask.Interval(90 * time.Second)
+
+	// then
+	if next := schedule.Next(now); !next.Equal(now.Add(90 * time.Second)) {
+		t.Errorf("expected next activation one interval later, got %s", next)
+	}
+}
+
+func intervalWithShardsTest(t *testing.T) {
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.TaskSchedule(crontask.Interval(time.Minute)),
+		crontask.Shards(3),
+	)
+
+	// then
+	if err == nil {
+		t.Error("Expected error, but none occurred")
+	}
+
+	if task != nil {
+		t.Error("Expected no task being returned, but was")
+	}
+}
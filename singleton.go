@@ -0,0 +1,206 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	leading    = int32(1)
+	notLeading = int32(0)
+)
+
+// SingletonFunc is a long-running function, that is called upon gaining
+// leadership. It must return as soon as its context is canceled. The cause of
+// the cancellation can be retrieved via context.Cause - see crontask.ErrStopped.
+type SingletonFunc func(ctx context.Context) error
+
+// Singleton describes a long-running function, which is continuously executed
+// on exactly one of all running instances. Leadership is retained via the same
+// redis lock and heartbeat, a SynchronizedCronTask uses for single executions.
+//
+// If the leadership is lost, the function is canceled, and all instances
+// continue to campaign for leadership. Thus, another instance takes over
+// within the lock timeout, if the leading instance dies.
+//
+// It supports graceful shutdowns via its Stop() function.
+type Singleton struct {
+	elector
+
+	singletonFunc SingletonFunc
+	lockTimeout   time.Duration
+	lockHeartbeat time.Duration
+
+	leadership   *int32
+	shutdownFunc context.CancelCauseFunc
+	done         chan struct{}
+}
+
+// NewSingletonWithOptions creates a new Singleton instance, which immediately
+// starts campaigning for leadership. Of the given options, only the name, the
+// logger, the keyspace, the registry, the lock timeout, the lock heartbeat, the
+// heartbeat policy and the clock are used. The lock heartbeat also acts as the interval, in which
+// leadership is campaigned for. Invalid options are reported by a single *ValidationError.
+func NewSingletonWithOptions(client redislock.RedisClient, singletonFunc SingletonFunc, options *TaskOptions) (*Singleton, error) {
+	if options.Logger == nil {
+		// Create a "noop" logger, so we don't have to check for
+		// the logger being nil
+		logger := logrus.New()
+		logger.Out = io.Discard
+
+		options.Logger = logger
+	}
+
+	if options.Clock == nil {
+		options.Clock = clock.Real()
+	}
+
+	if err := options.validateSingleton(); err != nil {
+		return nil, err
+	}
+
+	shutdownCtx, shutdownFunc := context.WithCancelCause(context.Background())
+
+	singleton := &Singleton{
+		elector: newElector(client, options),
+
+		singletonFunc: singletonFunc,
+		lockTimeout:   options.LockTimeout,
+		lockHeartbeat: options.LockHeartbeat,
+
+		leadership:   new(int32),
+		shutdownFunc: shutdownFunc,
+		done:         make(chan struct{}),
+	}
+
+	go singleton.campaign(shutdownCtx)
+
+	return singleton, nil
+}
+
+// NewSingleton creates a new Singleton instance, which immediately starts
+// campaigning for leadership.
+func NewSingleton(client redislock.RedisClient, singletonFunc SingletonFunc, setters ...TaskOption) (*Singleton, error) {
+	// Default Options
+	args := &TaskOptions{
+		Name: DefaultName,
+
+		Logger: logrus.StandardLogger(),
+
+		LockTimeout:   DefaultLockTimeout,
+		LockHeartbeat: DefaultLockHeartbeat,
+	}
+
+	for _, setter := range setters {
+		setter(args)
+	}
+
+	return NewSingletonWithOptions(client, singletonFunc, args)
+}
+
+// Name returns the name of the singleton.
+func (singleton *Singleton) Name() string {
+	return singleton.name
+}
+
+// IsLeader returns true, if this instance currently holds the leadership,
+// and thus executes the singleton function.
+func (singleton *Singleton) IsLeader() bool {
+	return atomic.LoadInt32(singleton.leadership) == leading
+}
+
+// Stop gracefully stops the singleton. If this instance is the current leader,
+// the singleton function is canceled, and the leadership is resigned.
+func (singleton *Singleton) Stop(ctx context.Context) {
+	singleton.shutdownFunc(ErrStopped)
+	singleton.deregister()
+
+	select {
+	case <-ctx.Done():
+	case <-singleton.done:
+	}
+}
+
+// campaign continuously competes for leadership, until the context is canceled.
+func (singleton *Singleton) campaign(ctx context.Context) {
+	defer close(singleton.done)
+
+	timer := singleton.clock.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+		}
+
+		lock, err := singleton.obtain(ctx, singleton.keyspace.key(singleton.name, "lock"), singleton.lockTimeout)
+		if err != nil {
+			if errors.Is(err, redislock.ErrNotObtained) {
+				singleton.logger.Tracef("Could not gain leadership for singleton %q - retrying in %s", singleton.name, singleton.lockHeartbeat)
+			} else if ctx.Err() == nil {
+				singleton.logger.Errorf("Error while trying to gain leadership for singleton %q: %s", singleton.name, err)
+			}
+		} else if err := singleton.lead(ctx, lock); err != nil {
+			if errors.Is(err, ErrStopped) {
+				singleton.logger.Infof("Giving up leadership for singleton %q, as it is stopping", singleton.name)
+			} else {
+				singleton.logger.Errorf("Lost leadership for singleton %q: %s", singleton.name, err)
+			}
+		} else {
+			singleton.logger.Infof("Singleton %q returned while being leader - resigning leadership", singleton.name)
+		}
+
+		timer.Reset(singleton.lockHeartbeat)
+	}
+}
+
+// lead executes the singleton function, while the leadership lock is retained.
+func (singleton *Singleton) lead(ctx context.Context, lock *redislock.Lock) error {
+	defer func() {
+		// The context is usually canceled at this point, as the singleton
+		// is stopping. So resign with a separate one, to allow another
+		// instance to take over immediately.
+		releaseCtx, cancel := context.WithTimeout(context.Background(), singleton.lockTimeout)
+		defer cancel()
+
+		singleton.logger.Tracef("Resigning leadership for singleton %q", singleton.name)
+		if err := singleton.release(releaseCtx, lock); err != nil {
+			singleton.logger.Warnf("Failed to resign leadership for singleton %q: %s - the service should be able to recover from this", singleton.name, err)
+		}
+	}()
+
+	singleton.logger.Infof("Gained leadership for singleton %q", singleton.name)
+
+	atomic.StoreInt32(singleton.leadership, leading)
+	defer atomic.StoreInt32(singleton.leadership, notLeading)
+
+	// Wrap the context, so we can signal into the go routine if we need to abort mid-lock
+	wrappedContext, cancelFunc := context.WithCancelCause(ctx)
+	defer cancelFunc(nil)
+
+	doneChannel := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		doneChannel <- singleton.singletonFunc(wrappedContext)
+	}()
+
+	err := singleton.blockForFinish(wrappedContext, doneChannel, lock, singleton.lockTimeout, singleton.lockHeartbeat)
+
+	// Wait for the function to actually return, so it never runs
+	// concurrently with itself after regaining leadership.
+	cancelFunc(err)
+	<-finished
+
+	return err
+}
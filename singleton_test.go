@@ -0,0 +1,139 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Singleton(t *testing.T) {
+	t.Run("invalid-options", invalidSingletonOptionsTest)
+
+	redisVersions := []string{
+		"5-alpine",
+		"6-alpine",
+		"7-alpine",
+	}
+	for i := range redisVersions {
+		version := redisVersions[i]
+
+		t.Run(fmt.Sprintf("redis:%s", version), func(t *testing.T) {
+			t.Parallel()
+
+			t.Run("handover-test", handoverTest(version))
+		})
+	}
+}
+
+func invalidSingletonOptionsTest(t *testing.T) {
+	// given
+	options := &crontask.TaskOptions{
+		Name:          crontask.DefaultName,
+		LockTimeout:   -time.Second,
+		LockHeartbeat: 0,
+	}
+
+	// when
+	singleton, err := crontask.NewSingletonWithOptions(nil, func(ctx context.Context) error {
+		t.Error("singleton function must not be executed")
+		return nil
+	}, options)
+
+	// then
+	var validationErr *crontask.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	if singleton != nil {
+		t.Error("expected no singleton being returned, but was")
+	}
+
+	invalid := map[string]bool{}
+	for _, optionErr := range validationErr.Errors {
+		invalid[optionErr.Option] = true
+	}
+
+	for _, option := range []string{"LockTimeout", "LockHeartbeat"} {
+		if !invalid[option] {
+			t.Errorf("expected option %s to be reported as invalid, got %v", option, validationErr.Errors)
+		}
+	}
+}
+
+func handoverTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		running := new(int32)
+		singletonFunc := func(ctx context.Context) error {
+			if atomic.AddInt32(running, 1) > 1 {
+				t.Error("singleton function is running concurrently")
+			}
+			defer atomic.AddInt32(running, -1)
+
+			<-ctx.Done()
+			return nil
+		}
+
+		first, err := crontask.NewSingleton(
+			client,
+			singletonFunc,
+			crontask.LockHeartbeat(50*time.Millisecond),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Wait for the first singleton to gain leadership
+		time.Sleep(100 * time.Millisecond)
+
+		second, err := crontask.NewSingleton(
+			client,
+			singletonFunc,
+			crontask.LockHeartbeat(50*time.Millisecond),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer second.Stop(context.Background())
+
+		time.Sleep(100 * time.Millisecond)
+
+		// then
+		if !first.IsLeader() || second.IsLeader() {
+			t.Fatal("expected first singleton to be the only leader")
+		}
+
+		// when
+		first.Stop(context.Background())
+		time.Sleep(200 * time.Millisecond)
+
+		// then
+		if first.IsLeader() || !second.IsLeader() {
+			t.Fatal("expected second singleton to take over leadership")
+		}
+
+		logContains(
+			t, hook,
+
+			"Gained leadership for singleton",
+			"Giving up leadership for singleton",
+		)
+	}
+}
@@ -1,6 +1,8 @@
 package crontask
 
 import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
 	"github.com/bsm/redislock"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
@@ -39,6 +41,19 @@ const (
 	// acquired lock should be renewed (to the total of the leadership
 	// timeout).
 	DefaultLockHeartbeat = 1 * time.Second
+
+	// DefaultDependencyPollInterval is the default interval, in which a
+	// synchronized cron task with dependencies checks if all of its
+	// upstream tasks have completed the current slot.
+	DefaultDependencyPollInterval = 1 * time.Second
+)
+
+// errElectionInProgress signals that a firing was skipped, as the
+// leadership is already owned by a previous firing.
+var errElectionInProgress = errors.New("election already in progress")
+
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
 )
 
 // SynchronizedCronTask describes a task, which is identified by a cron expression and a
@@ -46,15 +61,32 @@ const (
 //
 // It supports graceful shutdowns via its Stop() function.
 type SynchronizedCronTask struct {
-	name string
+	elector
 
-	cron   *cron.Cron
-	locker *redislock.Client
+	cron     runner
+	schedule cron.Schedule
+	interval *intervalSchedule
 
-	logger *logrus.Logger
+	dependencies           []string
+	dependencyPollInterval time.Duration
+
+	shards int
+
+	taskFunc          TaskFunc
+	completionHold    time.Duration
+	leadershipTimeout time.Duration
+	softTimeout       time.Duration
+	lockTimeout       time.Duration
+	lockHeartbeat     time.Duration
+
+	health *healthTracker
+
+	clockSkew           *clockSkewTracker
+	clockSkewCorrection bool
 
 	electionInProgress *int32
-	shutdownFunc       func()
+	shutdownCtx        context.Context
+	shutdownFunc       context.CancelCauseFunc
 }
 
 func (synchronizedCronTask *SynchronizedCronTask) MarshalJSON() ([]byte, error) {
@@ -79,14 +111,17 @@ func (synchronizedCronTask *SynchronizedCronTask) Stop(ctx context.Context) {
 	case <-synchronizedCronTask.cron.Stop().Done():
 	}
 
-	synchronizedCronTask.shutdownFunc()
+	synchronizedCronTask.shutdownFunc(ErrStopped)
+	synchronizedCronTask.deregister()
 
-	// Allow everything to be properly gc'd
-	synchronizedCronTask.electionInProgress = nil
+	// Allow everything to be properly gc'd. The election guard is kept,
+	// as an execution might still be running after the context is canceled.
 	synchronizedCronTask.cron = nil
 }
 
-// TaskFunc is a function, that is called upon the cron firing.
+// TaskFunc is a function, that is called upon the cron firing. The cause of
+// the cancellation of its context can be retrieved via context.Cause - see
+// crontask.ErrStopped, crontask.ErrLeadershipTimeout and crontask.ErrLockLost.
 type TaskFunc func(ctx context.Context, task Task) error
 
 // Task is an abstraction of a running task.
@@ -124,7 +159,8 @@ func (l logrusCronLoggerBridge) translateKeysAndValues(keysAndValues []interface
 }
 
 // NewSynchronizedCronTaskWithOptions creates a new SynchronizedCronTask instance, or errors out
-// if the provided cron expression was invalid.
+// if the provided options are invalid. All invalid options are reported by a single *ValidationError.
+// Valid but suspicious options - such as runs which can overlap their own schedule - are logged.
 func NewSynchronizedCronTaskWithOptions(client redislock.RedisClient, taskFunc TaskFunc, options *TaskOptions) (*SynchronizedCronTask, error) {
 	if options.Logger == nil {
 		// Create a "noop" logger, so we don't have to check for
@@ -135,65 +171,108 @@ func NewSynchronizedCronTaskWithOptions(client redislock.RedisClient, taskFunc T
 		options.Logger = logger
 	}
 
-	shutdownCtx, leadershipCancel := context.WithCancel(context.Background())
+	if options.Clock == nil {
+		options.Clock = clock.Real()
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	schedule, err := parseSchedule(options)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, warning := range options.warnings(schedule, options.Clock.Now().UTC()) {
+		options.Logger.Warnf("Suspicious options for synchronized task %q: %s", options.Name, warning)
+	}
+
+	// Interval schedules keep track of the due time of their task,
+	// so every task gets its own copy.
+	var interval *intervalSchedule
+	if intervalOption, ok := schedule.(*intervalSchedule); ok {
+		interval = &intervalSchedule{interval: intervalOption.interval}
+		schedule = interval
+	}
+
+	if options.Calendar != nil {
+		schedule = options.Calendar.Schedule(schedule)
+	}
+
+	healthFailureThreshold := options.HealthFailureThreshold
+	if healthFailureThreshold == 0 {
+		healthFailureThreshold = DefaultHealthFailureThreshold
+	}
+
+	shutdownCtx, leadershipCancel := context.WithCancelCause(context.Background())
 
 	cronOptions := []cron.Option{
 		cron.WithLocation(time.UTC),
 		cron.WithLogger(logrusCronLoggerBridge{options.Logger}),
-		cron.WithParser(cron.NewParser(
-			cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-		)),
 	}
 
 	synchronizedTask := &SynchronizedCronTask{
-		name: options.Name,
+		elector: newElector(client, options),
 
-		cron:   cron.New(cronOptions...),
-		locker: redislock.New(client),
+		cron:     newRunner(options.Clock, cronOptions...),
+		schedule: schedule,
+		interval: interval,
 
-		logger: options.Logger,
+		dependencies:           options.Dependencies,
+		dependencyPollInterval: options.DependencyPollInterval,
+
+		shards: options.Shards,
+
+		taskFunc:          taskFunc,
+		completionHold:    options.CompletionHold,
+		leadershipTimeout: options.LeadershipTimeout,
+		softTimeout:       options.SoftTimeout,
+		lockTimeout:       options.LockTimeout,
+		lockHeartbeat:     options.LockHeartbeat,
+
+		health: &healthTracker{failureThreshold: healthFailureThreshold, clock: options.Clock},
+
+		clockSkew:           &clockSkewTracker{},
+		clockSkewCorrection: options.ClockSkewCorrection,
 
 		electionInProgress: new(int32),
+		shutdownCtx:        shutdownCtx,
 		shutdownFunc:       leadershipCancel,
 	}
 
-	_, err := synchronizedTask.cron.AddFunc(options.CronExpression, func() {
-		if atomic.LoadInt32(synchronizedTask.electionInProgress) == electing {
-			synchronizedTask.logger.Tracef("Skipping election for synchronized task %q, as leadership is already owned", synchronizedTask.name)
-			return
-		}
+	// The cron fires on the local clock, so it is shifted onto the clock of redis if requested
+	cronSchedule := schedule
+	if options.ClockSkewCorrection {
+		cronSchedule = &correctedSchedule{schedule: schedule, tracker: synchronizedTask.clockSkew}
+	}
 
-		atomic.StoreInt32(synchronizedTask.electionInProgress, electing)
-		defer func() {
-			atomic.StoreInt32(synchronizedTask.electionInProgress, notElecting)
-		}()
+	synchronizedTask.cron.Schedule(cronSchedule, cron.FuncJob(func() {
+		_ = synchronizedTask.fire(time.Time{}, "")
+	}))
 
-		// --------------
+	if options.TriggerClient != nil {
+		synchronizedTask.subscribeToTriggers(options.TriggerClient)
+	}
 
-		leadershipContext, cancel := context.WithDeadline(shutdownCtx, time.Now().Add(options.LeadershipTimeout))
-		defer cancel()
+	// Only measure the clock skew if it is used, as every measurement costs a
+	// round trip to redis. Without a client there is no redis clock to compare against.
+	clockSkewInterval := options.ClockSkewInterval
+	if clockSkewInterval == 0 && (options.ClockSkewCorrection || options.ClockSkewHandler != nil) {
+		clockSkewInterval = DefaultClockSkewInterval
+	}
 
-		start := time.Now()
-		if err := synchronizedTask.handleElectionAttempt(
-			leadershipContext,
-			options.LockTimeout,
-			options.LockHeartbeat,
-			taskFunc,
-		); err != nil {
-			if errors.Is(err, redislock.ErrNotObtained) {
-				synchronizedTask.logger.Debugf("Could not gain temporary leadership for synchronized task %q - ignoring", synchronizedTask.name)
-			} else if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				synchronizedTask.logger.Errorf("Forcefully giving up leadership for synchronized task %q - timeout of %s reached", synchronizedTask.name, options.LeadershipTimeout)
-			} else {
-				synchronizedTask.logger.Errorf("Error while trying to temporarily gain leadership for synchronized task %q: %s", synchronizedTask.name, err)
-			}
-		} else {
-			synchronizedTask.logger.Infof("Successfully executed synchronized task %q in %s", synchronizedTask.name, time.Since(start))
+	if clockSkewInterval > 0 && client != nil {
+		clockSkewThreshold := options.ClockSkewThreshold
+		if clockSkewThreshold == 0 {
+			clockSkewThreshold = DefaultClockSkewThreshold
 		}
-	})
-	if err != nil {
-		leadershipCancel()
-		return nil, err
+
+		go synchronizedTask.monitorClockSkew(
+			clockSkewInterval,
+			time.Duration(clockSkewThreshold*float64(options.LockTimeout)),
+			options.ClockSkewHandler,
+		)
 	}
 
 	synchronizedTask.cron.Start()
@@ -202,10 +281,19 @@ func NewSynchronizedCronTaskWithOptions(client redislock.RedisClient, taskFunc T
 }
 
 // NewSynchronizedCronTask creates a new SynchronizedCronTask instance, or errors out
-// if the provided cron expression was invalid.
+// if the provided options are invalid.
 func NewSynchronizedCronTask(client redislock.RedisClient, taskFunc TaskFunc, setters ...TaskOption) (*SynchronizedCronTask, error) {
-	// Default Options
-	args := &TaskOptions{
+	args := defaultTaskOptions()
+	for _, setter := range setters {
+		setter(args)
+	}
+
+	return NewSynchronizedCronTaskWithOptions(client, taskFunc, args)
+}
+
+// defaultTaskOptions returns the options used, if no setters are applied.
+func defaultTaskOptions() *TaskOptions {
+	return &TaskOptions{
 		Name: DefaultName,
 
 		Logger: logrus.StandardLogger(),
@@ -214,13 +302,9 @@ func NewSynchronizedCronTask(client redislock.RedisClient, taskFunc TaskFunc, se
 		LeadershipTimeout: DefaultLeadershipTimeout,
 		LockTimeout:       DefaultLockTimeout,
 		LockHeartbeat:     DefaultLockHeartbeat,
-	}
 
-	for _, setter := range setters {
-		setter(args)
+		DependencyPollInterval: DefaultDependencyPollInterval,
 	}
-
-	return NewSynchronizedCronTaskWithOptions(client, taskFunc, args)
 }
 
 // ExecuteNow forces the cron to fire immediately. Locking is still
@@ -231,7 +315,7 @@ func (synchronizedCronTask *SynchronizedCronTask) ExecuteNow() {
 		return
 	}
 
-	synchronizedCronTask.cron.Entries()[0].Job.Run()
+	_ = synchronizedCronTask.fire(synchronizedCronTask.clock.Now().UTC(), "")
 }
 
 // NextTime returns the next time the cron task will fire.
@@ -241,11 +325,167 @@ func (synchronizedCronTask *SynchronizedCronTask) NextTime() time.Time {
 		return time.Time{}
 	}
 
-	return synchronizedCronTask.cron.Entries()[0].Schedule.Next(time.Now())
+	return synchronizedCronTask.schedule.Next(synchronizedCronTask.clock.Now())
+}
+
+// fire handles a single firing of the cron, or a manual execution. The run
+// identifies manual executions across instances, and is zero for firings of
+// the cron, which are identified by their slot instead. The trigger is the id
+// of the cluster-wide trigger of the execution - if any.
+func (synchronizedCronTask *SynchronizedCronTask) fire(run time.Time, trigger string) (err error) {
+	if atomic.LoadInt32(synchronizedCronTask.electionInProgress) == electing {
+		synchronizedCronTask.logger.Tracef("Skipping election for synchronized task %q, as leadership is already owned", synchronizedCronTask.name)
+		return electionError{errElectionInProgress}
+	}
+
+	atomic.StoreInt32(synchronizedCronTask.electionInProgress, electing)
+	defer func() {
+		atomic.StoreInt32(synchronizedCronTask.electionInProgress, notElecting)
+	}()
+
+	defer func() {
+		synchronizedCronTask.health.record(err)
+	}()
+
+	// --------------
+
+	// Only firings of the cron are paused, manual executions are still honored
+	if run.IsZero() {
+		// Bound the check, so a slow redis can't stall the cron
+		pauseCtx, cancel := context.WithTimeout(synchronizedCronTask.shutdownCtx, synchronizedCronTask.lockTimeout)
+		paused, err := synchronizedCronTask.paused(pauseCtx)
+		cancel()
+		if err != nil {
+			synchronizedCronTask.logger.Warnf("Skipping firing of synchronized task %q, as its pause state is unknown: %s", synchronizedCronTask.name, err)
+			return electionError{err}
+		}
+
+		if paused {
+			synchronizedCronTask.logger.Debugf("Skipping firing of synchronized task %q, as it is paused", synchronizedCronTask.name)
+			return electionError{errPaused}
+		}
+	}
+
+	var slot time.Time
+	taskFunc := synchronizedCronTask.taskFunc
+	if synchronizedCronTask.interval == nil {
+		slot = previousActivation(synchronizedCronTask.schedule, synchronizedCronTask.now())
+	} else {
+		// Interval schedules have no fixed slots, so the slot of a
+		// firing is the time the interval passed since the last run.
+		if run.IsZero() {
+			due, err := synchronizedCronTask.awaitInterval(synchronizedCronTask.shutdownCtx)
+			if err != nil {
+				synchronizedCronTask.logger.Warnf("Skipping firing of synchronized task %q: %s", synchronizedCronTask.name, err)
+				return electionError{err}
+			}
+
+			slot = due.Truncate(time.Millisecond)
+		} else {
+			slot = run
+		}
+
+		taskFunc = synchronizedCronTask.wrapIntervalFunc(!run.IsZero(), taskFunc)
+	}
+
+	// Sharded slots are completed by the instance finishing the last shard instead
+	if synchronizedCronTask.shards <= 1 {
+		taskFunc = synchronizedCronTask.wrapCompletionFunc(slot, taskFunc)
+	}
+
+	if synchronizedCronTask.completionHold > 0 {
+		taskFunc = synchronizedCronTask.wrapCompletionHoldFunc(!run.IsZero(), slot, taskFunc)
+	}
+
+	// Shards of a trigger are deduplicated by their states already, which are tracked by run
+	if trigger != "" && synchronizedCronTask.shards <= 1 {
+		taskFunc = synchronizedCronTask.wrapTriggerFunc(trigger, taskFunc)
+	}
+
+	if len(synchronizedCronTask.dependencies) > 0 {
+		if err := synchronizedCronTask.awaitDependencies(synchronizedCronTask.shutdownCtx, slot); err != nil {
+			synchronizedCronTask.logger.Warnf("Skipping slot %s of synchronized task %q: %s", slot, synchronizedCronTask.name, err)
+			return electionError{err}
+		}
+	}
+
+	leadershipContext, cancel := clock.WithDeadlineCause(
+		withSlot(synchronizedCronTask.shutdownCtx, slot),
+		synchronizedCronTask.clock,
+		synchronizedCronTask.clock.Now().Add(synchronizedCronTask.leadershipTimeout),
+		ErrLeadershipTimeout,
+	)
+	defer cancel()
+
+	softDeadline := synchronizedCronTask.startSoftDeadline()
+	defer softDeadline.stop()
+
+	if softDeadline != nil {
+		leadershipContext = withSoftDeadline(leadershipContext, softDeadline.done)
+	}
+
+	start := synchronizedCronTask.clock.Now()
+
+	// Shards of manual executions are tracked separately from the
+	// slot, so they do not collide with already finished firings.
+	if run.IsZero() {
+		run = slot
+	}
+
+	if synchronizedCronTask.shards > 1 {
+		err = synchronizedCronTask.handleShardedElectionAttempts(
+			leadershipContext,
+			run,
+			synchronizedCronTask.lockTimeout,
+			synchronizedCronTask.lockHeartbeat,
+			taskFunc,
+		)
+	} else {
+		err = synchronizedCronTask.handleElectionAttempt(
+			leadershipContext,
+			synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "lock"),
+			synchronizedCronTask.lockTimeout,
+			synchronizedCronTask.lockHeartbeat,
+			taskFunc,
+		)
+	}
+
+	// Failed, aborted or forcefully stopped executions did not stop voluntarily
+	if softDeadline.wasReached() && err == nil {
+		synchronizedCronTask.logger.Infof("Synchronized task %q stopped voluntarily after its soft timeout", synchronizedCronTask.name)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, redislock.ErrNotObtained):
+			synchronizedCronTask.logger.Debugf("Could not gain temporary leadership for synchronized task %q - ignoring", synchronizedCronTask.name)
+		case errors.Is(err, errNotDue):
+			synchronizedCronTask.logger.Debugf("Synchronized task %q was just executed by another instance - ignoring", synchronizedCronTask.name)
+		case errors.Is(err, errTriggerHandled):
+			synchronizedCronTask.logger.Debugf("Trigger of synchronized task %q was already handled by another instance - ignoring", synchronizedCronTask.name)
+		case errors.Is(err, errCompletionHeld):
+			synchronizedCronTask.logger.Debugf("Synchronized task %q already completed slot %s on another instance - ignoring", synchronizedCronTask.name, slot)
+		case errors.Is(err, ErrStopped), errors.Is(err, context.Canceled):
+			synchronizedCronTask.logger.Warnf("Aborted synchronized task %q, as it was stopped", synchronizedCronTask.name)
+		case errors.Is(err, ErrLeadershipTimeout) && softDeadline.wasReached():
+			synchronizedCronTask.logger.Errorf("Forcefully giving up leadership for synchronized task %q - timeout of %s reached, as it did not stop after its soft timeout", synchronizedCronTask.name, synchronizedCronTask.leadershipTimeout)
+		case errors.Is(err, ErrLeadershipTimeout):
+			synchronizedCronTask.logger.Errorf("Forcefully giving up leadership for synchronized task %q - timeout of %s reached", synchronizedCronTask.name, synchronizedCronTask.leadershipTimeout)
+		case errors.Is(err, ErrLockLost):
+			synchronizedCronTask.logger.Errorf("Lost leadership for synchronized task %q while executing: %s", synchronizedCronTask.name, err)
+		default:
+			synchronizedCronTask.logger.Errorf("Error while trying to temporarily gain leadership for synchronized task %q: %s", synchronizedCronTask.name, err)
+		}
+	} else {
+		synchronizedCronTask.logger.Infof("Successfully executed synchronized task %q in %s", synchronizedCronTask.name, synchronizedCronTask.clock.Now().Sub(start))
+	}
+
+	return err
 }
 
 func (synchronizedCronTask *SynchronizedCronTask) handleElectionAttempt(
 	ctx context.Context,
+	lockKey string,
 	lockTimeout time.Duration,
 	lockHeartbeat time.Duration,
 	taskFunc TaskFunc,
@@ -255,65 +495,196 @@ func (synchronizedCronTask *SynchronizedCronTask) handleElectionAttempt(
 	// Try to lock
 	logger.Tracef("Trying to temporarily gain leadership for synchronized task %q", synchronizedCronTask.name)
 
-	lock, err := synchronizedCronTask.locker.Obtain(
-		ctx,
-		fmt.Sprintf("%s.lock", synchronizedCronTask.name),
-		lockTimeout,
-		nil,
-	)
+	lock, err := synchronizedCronTask.obtain(ctx, lockKey, lockTimeout)
 	if err != nil {
-		return err
+		return electionError{err}
 	}
 
 	defer func() {
+		// The context is usually canceled at this point in case of timeouts or
+		// shutdowns. So resign with a separate one, to allow another instance
+		// to take over immediately.
+		releaseCtx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+		defer cancel()
+
 		logger.Tracef("Resigning temporary leadership for synchronized task %q", synchronizedCronTask.name)
-		if err := lock.Release(ctx); err != nil {
+		if err := synchronizedCronTask.release(releaseCtx, lock); err != nil {
 			logger.Warnf("Failed to resign leadership for synchronized task %q: %s - the service should be able to recover from this", synchronizedCronTask.name, err)
 		}
 	}()
 
-	// Heartbeat ticker to retain the lock while we execute the handler
-	ticker := time.NewTicker(lockHeartbeat)
-	defer ticker.Stop()
-
 	// Wrap the context, so we can signal into the go routine if we need to abort mid-lock
-	wrappedContext, cancelFunc := context.WithCancel(ctx)
-	defer cancelFunc()
+	wrappedContext, cancelFunc := context.WithCancelCause(ctx)
 
 	doneChannel := make(chan error, 1)
 	go func() {
 		doneChannel <- taskFunc(wrappedContext, synchronizedCronTask)
 	}()
 
-	return synchronizedCronTask.blockForFinish(wrappedContext, doneChannel, ticker, lock, lockTimeout)
+	err = synchronizedCronTask.blockForFinish(wrappedContext, doneChannel, lock, lockTimeout, lockHeartbeat)
+
+	// Signals the cause to the task function, if it is still running - e.g. as the lock was lost
+	cancelFunc(err)
+
+	return err
+}
+
+// elector bundles everything required to compete for, and retain the
+// leadership of a named lock.
+type elector struct {
+	name     string
+	keyspace Keyspace
+
+	client redislock.RedisClient
+	locker *redislock.Client
+	clock  clock.Clock
+
+	registry *Registry
+
+	heartbeatMaxFailures  int
+	heartbeatSafetyMargin time.Duration
+
+	logger *logrus.Logger
 }
 
-func (synchronizedCronTask *SynchronizedCronTask) blockForFinish(ctx context.Context,
-	doneChannel chan error, ticker *time.Ticker,
-	lock *redislock.Lock, lockTimeout time.Duration,
+// newElector creates a new elector, and adds it to the registry - if any.
+func newElector(client redislock.RedisClient, options *TaskOptions) elector {
+	if options.Registry != nil {
+		options.Registry.addTask(options.Name)
+	}
+
+	return elector{
+		name:     options.Name,
+		keyspace: options.Keyspace,
+		client:   client,
+		locker:   redislock.New(client),
+		clock:    options.Clock,
+		registry: options.Registry,
+
+		heartbeatMaxFailures:  options.HeartbeatMaxFailures,
+		heartbeatSafetyMargin: options.HeartbeatSafetyMargin,
+
+		logger: options.Logger,
+	}
+}
+
+// obtain tries to obtain the lock with the given key. If the elector is part of
+// a registry, the running instance is recorded as the holder of the lock.
+func (elector *elector) obtain(ctx context.Context, lockKey string, lockTimeout time.Duration) (*redislock.Lock, error) {
+	lock, err := elector.locker.Obtain(ctx, lockKey, lockTimeout, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	elector.recordHolder(ctx, lock, lockTimeout)
+
+	return lock, nil
+}
+
+// release releases the given lock, and clears the record of its holder.
+func (elector *elector) release(ctx context.Context, lock *redislock.Lock) error {
+	elector.clearHolder(ctx, lock)
+
+	return lock.Release(ctx)
+}
+
+// deregister removes the elector from the registry - if any.
+func (elector *elector) deregister() {
+	if elector.registry != nil {
+		elector.registry.removeTask(elector.name)
+	}
+}
+
+// blockForFinish blocks until the task function is done, while the lock is
+// renewed with the given heartbeat. Failed renewals are retried with backoff,
+// until the lock expires (minus the safety margin), or the maximum amount of
+// consecutive failures is reached. Then, the lock is considered lost.
+func (elector *elector) blockForFinish(ctx context.Context,
+	doneChannel chan error, lock *redislock.Lock,
+	lockTimeout time.Duration, lockHeartbeat time.Duration,
 ) error {
-	logger := synchronizedCronTask.logger.WithContext(ctx).WithField("task_name", synchronizedCronTask.name)
+	logger := elector.logger.WithContext(ctx).WithField("task_name", elector.name)
+
+	// Heartbeat timer to retain the lock while we execute the handler
+	heartbeat := elector.clock.NewTimer(lockHeartbeat)
+	defer heartbeat.Stop()
+
+	// The lock was obtained (or renewed) right before, so this
+	// errs on the side of caution.
+	expiry := elector.clock.Now().Add(lockTimeout)
+	failures := 0
 
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return context.Cause(ctx)
 		case err := <-doneChannel:
 			if err != nil {
-				return fmt.Errorf("error while executing synchronized task function %q: %w", synchronizedCronTask.name, err)
+				return fmt.Errorf("error while executing synchronized task function %q: %w", elector.name, err)
 			}
 
 			return nil
-		case <-ticker.C:
-			// Renew the lock
-			if err := lock.Refresh(ctx, lockTimeout, nil); err != nil {
+		case <-heartbeat.C():
+			// Renew the lock - but never wait for redis beyond the expiry of the lock
+			attempt := elector.clock.Now()
+			refreshCtx, cancel := clock.WithDeadline(ctx, elector.clock, expiry.Add(-elector.heartbeatSafetyMargin))
+			err := lock.Refresh(refreshCtx, lockTimeout, nil)
+			if err == nil {
+				elector.renewHolder(refreshCtx, lock, lockTimeout)
+			}
+			cancel()
+
+			if err == nil {
+				expiry = attempt.Add(lockTimeout)
+				failures = 0
+
+				logger.Debugf("Renewed leadership lock for long running synchronized task %q fill", elector.name)
+				heartbeat.Reset(lockHeartbeat)
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+
+			failures++
+			remaining := expiry.Sub(elector.clock.Now()) - elector.heartbeatSafetyMargin
+
+			// The lock is held by another instance (or expired) already, or the policy forbids to retry
+			if errors.Is(err, redislock.ErrNotObtained) || remaining <= 0 ||
+				(elector.heartbeatMaxFailures > 0 && failures >= elector.heartbeatMaxFailures) {
 				return fmt.Errorf(
-					"failed to renew leadership for synchronized task %q lock while executing: %w - crudely canceling",
-					synchronizedCronTask.name, err,
+					"%w: failed to renew leadership for synchronized task %q lock while executing: %v - crudely canceling",
+					ErrLockLost, elector.name, err,
 				)
 			}
 
-			logger.Debugf("Renewed leadership lock for long running synchronized task %q fill", synchronizedCronTask.name)
+			backoff := heartbeatBackoff(lockHeartbeat, failures)
+			if backoff > remaining {
+				backoff = remaining
+			}
+
+			logger.Warnf(
+				"Failed to renew leadership for synchronized task %q (attempt %d): %s - retrying in %s, as the lock is still held for %s",
+				elector.name, failures, err, backoff, expiry.Sub(elector.clock.Now()),
+			)
+			heartbeat.Reset(backoff)
 		}
 	}
 }
+
+// heartbeatBackoff returns the time to wait before retrying a failed renewal of
+// a lock. It starts at a quarter of the heartbeat, and doubles with every failure
+// up to the heartbeat itself.
+func heartbeatBackoff(lockHeartbeat time.Duration, failures int) time.Duration {
+	backoff := lockHeartbeat / 4
+	for i := 1; i < failures && backoff < lockHeartbeat; i++ {
+		backoff *= 2
+	}
+
+	if backoff > lockHeartbeat {
+		return lockHeartbeat
+	}
+
+	return backoff
+}
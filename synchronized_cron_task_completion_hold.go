@@ -0,0 +1,44 @@
+package crontask
+
+import (
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"time"
+)
+
+var luaHoldCompletion = redis.NewScript(`return redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])`)
+
+// errCompletionHeld signals that a firing was skipped, as its slot
+// was completed by another instance just before.
+var errCompletionHeld = errors.New("slot already completed")
+
+// wrapCompletionHoldFunc wraps a task function, so that the completion of the
+// slot is held in redis while the lock is still held. Firings of a held slot are
+// skipped, unless they are manual executions.
+func (synchronizedCronTask *SynchronizedCronTask) wrapCompletionHoldFunc(manual bool, slot time.Time, taskFunc TaskFunc) TaskFunc {
+	key := synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "held")
+
+	return func(ctx context.Context, task Task) error {
+		if !manual {
+			held, err := synchronizedCronTask.timestamp(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			if held.Equal(slot) {
+				return electionError{errCompletionHeld}
+			}
+		}
+
+		if err := taskFunc(ctx, task); err != nil {
+			return err
+		}
+
+		return luaHoldCompletion.Run(
+			ctx, synchronizedCronTask.client, []string{key},
+			slot.UnixMilli(), synchronizedCronTask.completionHold.Milliseconds(),
+		).Err()
+	}
+}
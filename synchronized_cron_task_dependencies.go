@@ -0,0 +1,164 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	luaMarkCompleted = redis.NewScript(`local current = tonumber(redis.call("get", KEYS[1]) or "0") if tonumber(ARGV[1]) > current then return redis.call("set", KEYS[1], ARGV[1]) else return 0 end`)
+	luaLastCompleted = redis.NewScript(`return redis.call("get", KEYS[1]) or "0"`)
+)
+
+// maxSlotLookback is the maximum time span searched for the previous
+// activation of a schedule. This matches the cut-off of robfig/cron,
+// which gives up on schedules which do not fire within five years.
+const maxSlotLookback = 5 * 366 * 24 * time.Hour
+
+type slotContextKey struct{}
+
+// SlotFromContext returns the logical slot - that is, the scheduled time
+// of the cron firing - a task function is executed for. Manual executions
+// via ExecuteNow are attributed to the latest slot before the execution.
+func SlotFromContext(ctx context.Context) (time.Time, bool) {
+	slot, ok := ctx.Value(slotContextKey{}).(time.Time)
+	return slot, ok
+}
+
+func withSlot(ctx context.Context, slot time.Time) context.Context {
+	return context.WithValue(ctx, slotContextKey{}, slot)
+}
+
+// previousActivation returns the latest activation of the schedule, which is
+// not after the given time. If none can be found, the given time truncated to
+// the second is returned.
+func previousActivation(schedule cron.Schedule, now time.Time) time.Time {
+	// Schedules can only be asked for their next activation, so search
+	// backwards with an exponentially growing window, and then step forward.
+	for lookback := time.Second; lookback <= maxSlotLookback; lookback *= 2 {
+		activation := schedule.Next(now.Add(-lookback))
+		if activation.IsZero() || activation.After(now) {
+			continue
+		}
+
+		for {
+			next := schedule.Next(activation)
+			if next.IsZero() || next.After(now) {
+				return activation
+			}
+
+			activation = next
+		}
+	}
+
+	return now.Truncate(time.Second)
+}
+
+// markCompleted records in redis, that the given slot was successfully completed.
+func (synchronizedCronTask *SynchronizedCronTask) markCompleted(ctx context.Context, slot time.Time) error {
+	return luaMarkCompleted.Run(
+		ctx, synchronizedCronTask.client,
+		[]string{synchronizedCronTask.keyspace.key(synchronizedCronTask.name, "completed")},
+		slot.UnixMilli(),
+	).Err()
+}
+
+// wrapCompletionFunc wraps a task function, so that the successful completion of
+// the given slot is recorded in redis while the lock is still held. Thus, dependent
+// tasks never observe a slot as completed while it is still running.
+func (synchronizedCronTask *SynchronizedCronTask) wrapCompletionFunc(slot time.Time, taskFunc TaskFunc) TaskFunc {
+	return func(ctx context.Context, task Task) error {
+		if err := taskFunc(ctx, task); err != nil {
+			return err
+		}
+
+		return synchronizedCronTask.markCompleted(ctx, slot)
+	}
+}
+
+// lastCompleted returns the latest slot successfully completed by the task with the given name.
+func (synchronizedCronTask *SynchronizedCronTask) lastCompleted(ctx context.Context, name string) (time.Time, error) {
+	return synchronizedCronTask.timestamp(ctx, synchronizedCronTask.keyspace.key(name, "completed"))
+}
+
+// timestamp reads a timestamp in milliseconds from the given redis key.
+// If the key does not exist, the zero time is returned.
+func (synchronizedCronTask *SynchronizedCronTask) timestamp(ctx context.Context, key string) (time.Time, error) {
+	res, err := luaLastCompleted.Run(ctx, synchronizedCronTask.client, []string{key}).Text()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	millis, err := strconv.ParseInt(res, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if millis == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.UnixMilli(millis).UTC(), nil
+}
+
+// awaitDependencies blocks until all upstream tasks have completed the given
+// slot. Waiting is given up once the next slot of the task is reached.
+func (synchronizedCronTask *SynchronizedCronTask) awaitDependencies(ctx context.Context, slot time.Time) error {
+	if next := synchronizedCronTask.schedule.Next(slot); !next.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = clock.WithDeadline(ctx, synchronizedCronTask.clock, next)
+		defer cancel()
+	}
+
+	ticker := synchronizedCronTask.clock.NewTicker(synchronizedCronTask.dependencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending, err := synchronizedCronTask.pendingDependencies(ctx, slot)
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		synchronizedCronTask.logger.Tracef("Synchronized task %q is waiting for upstream tasks %q to complete slot %s", synchronizedCronTask.name, pending, slot)
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("upstream tasks %q did not complete the slot in time", pending)
+			}
+
+			return context.Cause(ctx)
+		case <-ticker.C():
+		}
+	}
+}
+
+// pendingDependencies returns the names of all upstream tasks, which have not
+// yet completed the given slot.
+func (synchronizedCronTask *SynchronizedCronTask) pendingDependencies(ctx context.Context, slot time.Time) ([]string, error) {
+	var pending []string
+	for _, dependency := range synchronizedCronTask.dependencies {
+		completed, err := synchronizedCronTask.lastCompleted(ctx, dependency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve completion state of upstream task %q: %w", dependency, err)
+		}
+
+		if completed.Before(slot) {
+			pending = append(pending, dependency)
+		}
+	}
+
+	return pending, nil
+}
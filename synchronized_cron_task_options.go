@@ -1,6 +1,9 @@
 package crontask
 
 import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -8,14 +11,45 @@ import (
 // TaskOptions bundles all available configuration
 // properties for a synchronized cron task.
 type TaskOptions struct {
-	Name           string
-	CronExpression string
+	Name            string
+	CronExpression  string
+	CronExpressions []string
+	ExtendedSyntax  bool
+	Schedule        Schedule
 
 	Logger *logrus.Logger
 
+	Keyspace Keyspace
+
 	LeadershipTimeout time.Duration
+	SoftTimeout       time.Duration
 	LockTimeout       time.Duration
 	LockHeartbeat     time.Duration
+
+	HeartbeatMaxFailures  int
+	HeartbeatSafetyMargin time.Duration
+
+	Dependencies           []string
+	DependencyPollInterval time.Duration
+
+	Shards int
+
+	CompletionHold time.Duration
+
+	Registry *Registry
+
+	TriggerClient redis.UniversalClient
+
+	Calendar *Calendar
+
+	HealthFailureThreshold int
+
+	ClockSkewInterval   time.Duration
+	ClockSkewThreshold  float64
+	ClockSkewHandler    ClockSkewHandlerFunc
+	ClockSkewCorrection bool
+
+	Clock clock.Clock
 }
 
 // TaskOption represents an option for a synchronized cron task.
@@ -37,6 +71,36 @@ func CronExpression(cronExpression string) TaskOption {
 	}
 }
 
+// CronExpressions sets several cron expressions for the synchronized cron task,
+// whose union forms its schedule. Coincident firings of several expressions
+// result in a single firing. If set, CronExpression is ignored.
+// The default is nil, which uses CronExpression only.
+func CronExpressions(cronExpressions ...string) TaskOption {
+	return func(c *TaskOptions) {
+		c.CronExpressions = cronExpressions
+	}
+}
+
+// ExtendedCronSyntax enables Quartz-style tokens (L, W and #) in the day fields
+// of the cron expressions of the synchronized cron task. See
+// crontask.ParseExtendedSchedule for details.
+// The default is false, which only allows the regular syntax.
+func ExtendedCronSyntax(enabled bool) TaskOption {
+	return func(c *TaskOptions) {
+		c.ExtendedSyntax = enabled
+	}
+}
+
+// TaskSchedule sets the schedule of the synchronized cron task. Besides any
+// cron.Schedule, the built-in schedules crontask.Interval, crontask.At and
+// crontask.After are available. If set, all cron expressions are ignored.
+// The default is nil, which uses the cron expressions.
+func TaskSchedule(schedule Schedule) TaskOption {
+	return func(c *TaskOptions) {
+		c.Schedule = schedule
+	}
+}
+
 // Logger sets the logger of the synchronized cron task.
 // The default is the logrus global default logger.
 func Logger(logger *logrus.Logger) TaskOption {
@@ -45,6 +109,15 @@ func Logger(logger *logrus.Logger) TaskOption {
 	}
 }
 
+// TaskKeyspace sets the keyspace, which all redis keys and pub/sub channels of
+// the synchronized cron task are built with. See crontask.Keyspace for details.
+// The default is the zero Keyspace, which uses no namespace and no hash tags.
+func TaskKeyspace(keyspace Keyspace) TaskOption {
+	return func(c *TaskOptions) {
+		c.Keyspace = keyspace
+	}
+}
+
 // LeadershipTimeout sets the timeout of a single execution of the
 // synchronized cron task.
 // The default is crontask.DefaultLeadershipTimeout.
@@ -54,6 +127,17 @@ func LeadershipTimeout(leadershipTimeout time.Duration) TaskOption {
 	}
 }
 
+// SoftTimeout sets the time before the LeadershipTimeout is reached, at which
+// a single execution of the synchronized cron task is asked to stop. Task
+// functions receive the signal via crontask.SoftDeadlineFromContext, and may
+// use it to checkpoint their work before their context is cancelled.
+// The default is zero, which disables the soft timeout.
+func SoftTimeout(softTimeout time.Duration) TaskOption {
+	return func(c *TaskOptions) {
+		c.SoftTimeout = softTimeout
+	}
+}
+
 // LockTimeout sets the timeout for the lock of a single execution of the
 // synchronized cron task. It is good practice to keep the timeout small,
 // for fast failure detection.
@@ -74,3 +158,151 @@ func LockHeartbeat(lockHeartbeat time.Duration) TaskOption {
 		c.LockHeartbeat = lockHeartbeat
 	}
 }
+
+// HeartbeatMaxFailures sets the number of consecutive failed renewals of the
+// lock, after which an execution of the synchronized cron task is canceled with
+// crontask.ErrLockLost. Failed renewals are retried with backoff in the meantime.
+// The default is zero, which retries until the lock expires.
+func HeartbeatMaxFailures(maxFailures int) TaskOption {
+	return func(c *TaskOptions) {
+		c.HeartbeatMaxFailures = maxFailures
+	}
+}
+
+// HeartbeatSafetyMargin sets the time before the lock expires, at which retrying
+// failed renewals of the lock is given up, and an execution of the synchronized
+// cron task is canceled with crontask.ErrLockLost. This accounts for clock drift,
+// and the time the task function needs to react to the cancellation.
+// The default is zero, which retries until the lock expires.
+func HeartbeatSafetyMargin(safetyMargin time.Duration) TaskOption {
+	return func(c *TaskOptions) {
+		c.HeartbeatSafetyMargin = safetyMargin
+	}
+}
+
+// DependsOn sets the names of upstream synchronized cron tasks, which must
+// have successfully completed the same slot before this task is executed.
+// The default is no dependencies.
+func DependsOn(names ...string) TaskOption {
+	return func(c *TaskOptions) {
+		c.Dependencies = names
+	}
+}
+
+// DependencyPollInterval sets the interval, in which the completion state
+// of upstream tasks is checked, while waiting for them to complete.
+// The default is crontask.DefaultDependencyPollInterval.
+func DependencyPollInterval(dependencyPollInterval time.Duration) TaskOption {
+	return func(c *TaskOptions) {
+		c.DependencyPollInterval = dependencyPollInterval
+	}
+}
+
+// Shards splits every firing of the synchronized cron task into the given
+// amount of shards. Each shard is locked individually, so all running instances
+// can share the work of a single firing. A value of one or below disables
+// sharding.
+// The default is zero.
+func Shards(shards int) TaskOption {
+	return func(c *TaskOptions) {
+		c.Shards = shards
+	}
+}
+
+// CompletionHold sets the minimum duration, for which the successful completion
+// of a slot is held in redis. Within that duration, firings of the same slot are
+// skipped - e.g. by instances with lagging clocks, which fire after a fast
+// execution already released the lock. Manual executions are not affected.
+// The default is zero, which disables holding completions.
+func CompletionHold(completionHold time.Duration) TaskOption {
+	return func(c *TaskOptions) {
+		c.CompletionHold = completionHold
+	}
+}
+
+// InstanceRegistry adds the synchronized cron task to the given registry, and
+// attaches the id of the running instance to every lock obtained by the task.
+// The default is nil.
+func InstanceRegistry(registry *Registry) TaskOption {
+	return func(c *TaskOptions) {
+		c.Registry = registry
+	}
+}
+
+// ClusterTrigger subscribes the synchronized cron task to cluster-wide triggers
+// via redis pub/sub, using the given client. See crontask.TriggerCluster.
+// The default is nil, which disables cluster-wide triggers.
+func ClusterTrigger(client redis.UniversalClient) TaskOption {
+	return func(c *TaskOptions) {
+		c.TriggerClient = client
+	}
+}
+
+// BlackoutCalendar sets a calendar, whose exclusions - such as public holidays
+// or freeze windows - are skipped by the schedule of the synchronized cron task.
+// Manual executions via ExecuteNow or TriggerCluster are not affected.
+// The default is nil, which disables exclusions.
+func BlackoutCalendar(calendar *Calendar) TaskOption {
+	return func(c *TaskOptions) {
+		c.Calendar = calendar
+	}
+}
+
+// HealthFailureThreshold sets the number of consecutive failed elections, after
+// which the task is reported as not ready by Health. Zero uses the default.
+// The default is crontask.DefaultHealthFailureThreshold.
+func HealthFailureThreshold(threshold int) TaskOption {
+	return func(c *TaskOptions) {
+		c.HealthFailureThreshold = threshold
+	}
+}
+
+// ClockSkewInterval sets the interval, in which the skew of the local clock
+// against the clock of the redis server is measured. Zero disables measuring,
+// unless a ClockSkewHandler or ClockSkewCorrection is configured - which measure
+// every crontask.DefaultClockSkewInterval then.
+// The default is zero.
+func ClockSkewInterval(interval time.Duration) TaskOption {
+	return func(c *TaskOptions) {
+		c.ClockSkewInterval = interval
+	}
+}
+
+// ClockSkewThreshold sets the fraction of the LockTimeout, above which a measured
+// clock skew is logged as a warning. Zero uses the default.
+// The default is crontask.DefaultClockSkewThreshold.
+func ClockSkewThreshold(threshold float64) TaskOption {
+	return func(c *TaskOptions) {
+		c.ClockSkewThreshold = threshold
+	}
+}
+
+// ClockSkewHandler sets a function, which is called with every measurement of
+// the clock skew - e.g. to export it as a metric.
+// The default is nil.
+func ClockSkewHandler(handler ClockSkewHandlerFunc) TaskOption {
+	return func(c *TaskOptions) {
+		c.ClockSkewHandler = handler
+	}
+}
+
+// ClockSkewCorrection schedules the firings of the synchronized cron task on the
+// clock of the redis server, corrected by the measured clock skew - instead of the
+// local clock. The clock skew is measured every crontask.DefaultClockSkewInterval,
+// unless a ClockSkewInterval is configured.
+// The default is false.
+func ClockSkewCorrection(enabled bool) TaskOption {
+	return func(c *TaskOptions) {
+		c.ClockSkewCorrection = enabled
+	}
+}
+
+// Clock sets the clock, on which the synchronized cron task is scheduled, and
+// its heartbeats and timeouts run. A clock.Fake allows to drive all of them
+// in tests, without waiting for real time to pass.
+// The default is nil, which uses the real clock.
+func Clock(taskClock clock.Clock) TaskOption {
+	return func(c *TaskOptions) {
+		c.Clock = taskClock
+	}
+}
@@ -2,7 +2,9 @@ package crontask_test
 
 import (
 	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 
 	"testing"
@@ -39,6 +41,68 @@ func Test_TaskOption_CronExpression(t *testing.T) {
 	}
 }
 
+// Tests that the CronExpressions option correctly applies.
+func Test_TaskOption_CronExpressions(t *testing.T) {
+	// given
+	option := crontask.CronExpressions("foo", "bar")
+	options := &crontask.TaskOptions{CronExpressions: nil}
+
+	// when
+	option(options)
+
+	// then
+	if len(options.CronExpressions) != 2 || options.CronExpressions[0] != "foo" || options.CronExpressions[1] != "bar" {
+		t.Errorf("cron expressions not correctly applied, got %q", options.CronExpressions)
+	}
+}
+
+// Tests that the ExtendedCronSyntax option correctly applies.
+func Test_TaskOption_ExtendedCronSyntax(t *testing.T) {
+	// given
+	option := crontask.ExtendedCronSyntax(true)
+	options := &crontask.TaskOptions{ExtendedSyntax: false}
+
+	// when
+	option(options)
+
+	// then
+	if !options.ExtendedSyntax {
+		t.Error("extended syntax not correctly applied, got false")
+	}
+}
+
+// Tests that the TaskSchedule option correctly applies.
+func Test_TaskOption_TaskSchedule(t *testing.T) {
+	// given
+	expected := crontask.Interval(time.Minute)
+	option := crontask.TaskSchedule(expected)
+	options := &crontask.TaskOptions{Schedule: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Schedule != expected {
+		t.Errorf("schedule not correctly applied, expected %v got %v", expected, options.Schedule)
+	}
+}
+
+// Tests that the TaskKeyspace option correctly applies.
+func Test_TaskOption_TaskKeyspace(t *testing.T) {
+	// given
+	expected := crontask.Keyspace{Namespace: "bar", HashTags: true}
+	option := crontask.TaskKeyspace(expected)
+	options := &crontask.TaskOptions{Keyspace: crontask.Keyspace{Namespace: "foo"}}
+
+	// when
+	option(options)
+
+	// then
+	if options.Keyspace != expected {
+		t.Errorf("keyspace not correctly applied, got %v", options.Keyspace)
+	}
+}
+
 // Tests that the Logger option correctly applies.
 func Test_TaskOption_Logger(t *testing.T) {
 	// given
@@ -69,6 +133,21 @@ func Test_TaskOption_LeadershipTimeout(t *testing.T) {
 	}
 }
 
+// Tests that the SoftTimeout option correctly applies.
+func Test_TaskOption_SoftTimeout(t *testing.T) {
+	// given
+	option := crontask.SoftTimeout(5 * time.Second)
+	options := &crontask.TaskOptions{SoftTimeout: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.SoftTimeout != 5*time.Second {
+		t.Errorf("soft timeout not correctly applied, got %s", options.SoftTimeout)
+	}
+}
+
 // Tests that the LockTimeout option correctly applies.
 func Test_TaskOption_LockTimeout(t *testing.T) {
 	// given
@@ -98,3 +177,238 @@ func Test_TaskOption_LockHeartbeat(t *testing.T) {
 		t.Errorf("lock heartbeat not correctly applied, got %s", options.LockHeartbeat)
 	}
 }
+
+// Tests that the HeartbeatMaxFailures option correctly applies.
+func Test_TaskOption_HeartbeatMaxFailures(t *testing.T) {
+	// given
+	option := crontask.HeartbeatMaxFailures(5)
+	options := &crontask.TaskOptions{HeartbeatMaxFailures: 0}
+
+	// when
+	option(options)
+
+	// then
+	if options.HeartbeatMaxFailures != 5 {
+		t.Errorf("heartbeat max failures not correctly applied, got %d", options.HeartbeatMaxFailures)
+	}
+}
+
+// Tests that the HeartbeatSafetyMargin option correctly applies.
+func Test_TaskOption_HeartbeatSafetyMargin(t *testing.T) {
+	// given
+	option := crontask.HeartbeatSafetyMargin(time.Second)
+	options := &crontask.TaskOptions{HeartbeatSafetyMargin: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.HeartbeatSafetyMargin != time.Second {
+		t.Errorf("heartbeat safety margin not correctly applied, got %s", options.HeartbeatSafetyMargin)
+	}
+}
+
+// Tests that the DependsOn option correctly applies.
+func Test_TaskOption_DependsOn(t *testing.T) {
+	// given
+	option := crontask.DependsOn("foo", "bar")
+	options := &crontask.TaskOptions{Dependencies: nil}
+
+	// when
+	option(options)
+
+	// then
+	if len(options.Dependencies) != 2 || options.Dependencies[0] != "foo" || options.Dependencies[1] != "bar" {
+		t.Errorf("dependencies not correctly applied, got %q", options.Dependencies)
+	}
+}
+
+// Tests that the DependencyPollInterval option correctly applies.
+func Test_TaskOption_DependencyPollInterval(t *testing.T) {
+	// given
+	option := crontask.DependencyPollInterval(time.Second)
+	options := &crontask.TaskOptions{DependencyPollInterval: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.DependencyPollInterval != time.Second {
+		t.Errorf("dependency poll interval not correctly applied, got %s", options.DependencyPollInterval)
+	}
+}
+
+// Tests that the Shards option correctly applies.
+func Test_TaskOption_Shards(t *testing.T) {
+	// given
+	option := crontask.Shards(4)
+	options := &crontask.TaskOptions{Shards: 0}
+
+	// when
+	option(options)
+
+	// then
+	if options.Shards != 4 {
+		t.Errorf("shards not correctly applied, got %d", options.Shards)
+	}
+}
+
+// Tests that the InstanceRegistry option correctly applies.
+func Test_TaskOption_InstanceRegistry(t *testing.T) {
+	// given
+	option := crontask.InstanceRegistry(&crontask.Registry{})
+	options := &crontask.TaskOptions{Registry: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Registry == nil {
+		t.Error("registry not correctly applied, got nil")
+	}
+}
+
+// Tests that the ClusterTrigger option correctly applies.
+func Test_TaskOption_ClusterTrigger(t *testing.T) {
+	// given
+	option := crontask.ClusterTrigger(&redis.Client{})
+	options := &crontask.TaskOptions{TriggerClient: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.TriggerClient == nil {
+		t.Error("trigger client not correctly applied, got nil")
+	}
+}
+
+// Tests that the BlackoutCalendar option correctly applies.
+func Test_TaskOption_BlackoutCalendar(t *testing.T) {
+	// given
+	expected := crontask.NewCalendar(nil, crontask.ExcludeMonthly(-3, -1))
+	option := crontask.BlackoutCalendar(expected)
+	options := &crontask.TaskOptions{Calendar: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Calendar != expected {
+		t.Errorf("calendar not correctly applied, expected %v got %v", expected, options.Calendar)
+	}
+}
+
+// Tests that the CompletionHold option correctly applies.
+func Test_TaskOption_CompletionHold(t *testing.T) {
+	// given
+	option := crontask.CompletionHold(5 * time.Second)
+	options := &crontask.TaskOptions{CompletionHold: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.CompletionHold != 5*time.Second {
+		t.Errorf("completion hold not correctly applied, got %s", options.CompletionHold)
+	}
+}
+
+// Tests that the HealthFailureThreshold option correctly applies.
+func Test_TaskOption_HealthFailureThreshold(t *testing.T) {
+	// given
+	option := crontask.HealthFailureThreshold(5)
+	options := &crontask.TaskOptions{HealthFailureThreshold: 3}
+
+	// when
+	option(options)
+
+	// then
+	if options.HealthFailureThreshold != 5 {
+		t.Errorf("health failure threshold not correctly applied, got %d", options.HealthFailureThreshold)
+	}
+}
+
+// Tests that the ClockSkewInterval option correctly applies.
+func Test_TaskOption_ClockSkewInterval(t *testing.T) {
+	// given
+	option := crontask.ClockSkewInterval(time.Second)
+	options := &crontask.TaskOptions{ClockSkewInterval: time.Hour}
+
+	// when
+	option(options)
+
+	// then
+	if options.ClockSkewInterval != time.Second {
+		t.Errorf("clock skew interval not correctly applied, got %s", options.ClockSkewInterval)
+	}
+}
+
+// Tests that the ClockSkewThreshold option correctly applies.
+func Test_TaskOption_ClockSkewThreshold(t *testing.T) {
+	// given
+	option := crontask.ClockSkewThreshold(0.5)
+	options := &crontask.TaskOptions{ClockSkewThreshold: 0.1}
+
+	// when
+	option(options)
+
+	// then
+	if options.ClockSkewThreshold != 0.5 {
+		t.Errorf("clock skew threshold not correctly applied, got %g", options.ClockSkewThreshold)
+	}
+}
+
+// Tests that the ClockSkewHandler option correctly applies.
+func Test_TaskOption_ClockSkewHandler(t *testing.T) {
+	// given
+	called := false
+	option := crontask.ClockSkewHandler(func(string, crontask.ClockSkew) {
+		called = true
+	})
+	options := &crontask.TaskOptions{ClockSkewHandler: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.ClockSkewHandler == nil {
+		t.Fatal("clock skew handler not correctly applied, got nil")
+	}
+
+	options.ClockSkewHandler("some-task", crontask.ClockSkew{})
+	if !called {
+		t.Error("clock skew handler not correctly applied, applied handler was not called")
+	}
+}
+
+// Tests that the ClockSkewCorrection option correctly applies.
+func Test_TaskOption_ClockSkewCorrection(t *testing.T) {
+	// given
+	option := crontask.ClockSkewCorrection(true)
+	options := &crontask.TaskOptions{ClockSkewCorrection: false}
+
+	// when
+	option(options)
+
+	// then
+	if !options.ClockSkewCorrection {
+		t.Errorf("clock skew correction not correctly applied, got %t", options.ClockSkewCorrection)
+	}
+}
+
+// Tests that the Clock option correctly applies.
+func Test_TaskOption_Clock(t *testing.T) {
+	// given
+	fakeClock := clock.NewFake(time.Now())
+	option := crontask.Clock(fakeClock)
+	options := &crontask.TaskOptions{Clock: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Clock != fakeClock {
+		t.Errorf("clock not correctly applied, got %v", options.Clock)
+	}
+}
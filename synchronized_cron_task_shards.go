@@ -0,0 +1,239 @@
+package crontask
+
+import (
+	"github.com/bsm/redislock"
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	luaFinishShard = redis.NewScript(`redis.call("hset", KEYS[1], ARGV[1], ARGV[2]) return redis.call("pexpire", KEYS[1], ARGV[3])`)
+	luaShardStates = redis.NewScript(`return redis.call("hgetall", KEYS[1])`)
+)
+
+const (
+	shardSucceeded = "succeeded"
+	shardFailed    = "failed"
+
+	// shardStateTTL is the time the state of the shards of a single
+	// slot is retained in redis.
+	shardStateTTL = 24 * time.Hour
+)
+
+// errShardFinished signals that a shard was already finished by
+// another instance, right before its lock was obtained.
+var errShardFinished = errors.New("shard already finished")
+
+// Shard describes the part of the work of a single firing, which a task
+// function is executed for.
+type Shard struct {
+	Index int
+	Count int
+}
+
+type shardContextKey struct{}
+
+// ShardFromContext returns the shard a task function is executed for. This
+// is only the case, if the synchronized cron task is sharded.
+func ShardFromContext(ctx context.Context) (Shard, bool) {
+	shard, ok := ctx.Value(shardContextKey{}).(Shard)
+	return shard, ok
+}
+
+func withShard(ctx context.Context, shard Shard) context.Context {
+	return context.WithValue(ctx, shardContextKey{}, shard)
+}
+
+// handleShardedElectionAttempts competes for all unfinished shards of the given slot,
+// until every shard was finished by any instance. Shards whose lock expires without
+// being finished - e.g. because their instance died - are reclaimed this way.
+func (synchronizedCronTask *SynchronizedCronTask) handleShardedElectionAttempts(
+	ctx context.Context,
+	slot time.Time,
+	lockTimeout time.Duration,
+	lockHeartbeat time.Duration,
+	taskFunc TaskFunc,
+) error {
+	logger := synchronizedCronTask.logger.WithContext(ctx).WithField("task_name", synchronizedCronTask.name)
+
+	ticker := synchronizedCronTask.clock.NewTicker(lockHeartbeat)
+	defer ticker.Stop()
+
+	executed := 0
+	for {
+		states, err := synchronizedCronTask.shardStates(ctx, slot)
+		if err != nil {
+			if executed == 0 {
+				return electionError{err}
+			}
+
+			return err
+		}
+
+		finished := 0
+		for index := 0; index < synchronizedCronTask.shards; index++ {
+			if _, ok := states[index]; ok {
+				finished++
+			}
+		}
+
+		if finished == synchronizedCronTask.shards {
+			if executed == 0 {
+				return electionError{redislock.ErrNotObtained}
+			}
+
+			var failed []int
+			for index := 0; index < synchronizedCronTask.shards; index++ {
+				if states[index] == shardFailed {
+					failed = append(failed, index)
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("shards %v of synchronized task %q failed", failed, synchronizedCronTask.name)
+			}
+
+			return nil
+		}
+
+		executedBefore := executed
+		for index := 0; index < synchronizedCronTask.shards; index++ {
+			if _, finished := states[index]; finished {
+				continue
+			}
+
+			shard := Shard{Index: index, Count: synchronizedCronTask.shards}
+			err := synchronizedCronTask.handleElectionAttempt(
+				withShard(ctx, shard),
+				synchronizedCronTask.keyspace.key(synchronizedCronTask.name, fmt.Sprintf("shard.%d.lock", index)),
+				lockTimeout,
+				lockHeartbeat,
+				synchronizedCronTask.wrapShardFunc(slot, shard, taskFunc),
+			)
+
+			switch {
+			case errors.Is(err, redislock.ErrNotObtained), errors.Is(err, errShardFinished):
+				// Claimed or finished by another instance
+			case err != nil && ctx.Err() != nil:
+				return err
+			case err != nil:
+				executed++
+				logger.Errorf("Error while executing shard %d of synchronized task %q: %s", index, synchronizedCronTask.name, err)
+			default:
+				executed++
+				logger.Debugf("Successfully executed shard %d of synchronized task %q", index, synchronizedCronTask.name)
+			}
+		}
+
+		// Only wait, if there was nothing to do for this instance.
+		// Otherwise, check immediately for remaining shards.
+		if executed > executedBefore {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			if executed == 0 {
+				return electionError{context.Cause(ctx)}
+			}
+
+			return context.Cause(ctx)
+		case <-ticker.C():
+		}
+	}
+}
+
+// wrapShardFunc wraps a task function, so that the state of the shard is
+// recorded in redis while the shard lock is still held. The instance finishing
+// the last shard also records the completion of the slot.
+func (synchronizedCronTask *SynchronizedCronTask) wrapShardFunc(slot time.Time, shard Shard, taskFunc TaskFunc) TaskFunc {
+	return func(ctx context.Context, task Task) error {
+		// Another instance might have finished the shard, right before the lock was obtained
+		states, err := synchronizedCronTask.shardStates(ctx, slot)
+		if err != nil {
+			return err
+		}
+
+		if _, finished := states[shard.Index]; finished {
+			return errShardFinished
+		}
+
+		taskErr := taskFunc(ctx, task)
+
+		state := shardSucceeded
+		if taskErr != nil {
+			state = shardFailed
+		}
+
+		if err := luaFinishShard.Run(
+			ctx, synchronizedCronTask.client,
+			[]string{synchronizedCronTask.shardStateKey(slot)},
+			shard.Index, state, shardStateTTL.Milliseconds(),
+		).Err(); err != nil {
+			// If there was an task error, give that precedence over the redis error
+			if taskErr != nil {
+				return taskErr
+			}
+
+			return err
+		}
+
+		if taskErr != nil {
+			return taskErr
+		}
+
+		return synchronizedCronTask.completeShardedSlot(ctx, slot)
+	}
+}
+
+// completeShardedSlot records the completion of the slot in redis, if all shards
+// of the given run succeeded. This is called while the lock of the last finished
+// shard is still held, just like the completion of slots of unsharded tasks.
+func (synchronizedCronTask *SynchronizedCronTask) completeShardedSlot(ctx context.Context, run time.Time) error {
+	slot, ok := SlotFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	states, err := synchronizedCronTask.shardStates(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	for index := 0; index < synchronizedCronTask.shards; index++ {
+		if states[index] != shardSucceeded {
+			return nil
+		}
+	}
+
+	return synchronizedCronTask.markCompleted(ctx, slot)
+}
+
+// shardStates returns the states of all finished shards of the given slot.
+func (synchronizedCronTask *SynchronizedCronTask) shardStates(ctx context.Context, slot time.Time) (map[int]string, error) {
+	res, err := luaShardStates.Run(ctx, synchronizedCronTask.client, []string{synchronizedCronTask.shardStateKey(slot)}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[int]string, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		index, err := strconv.Atoi(res[i])
+		if err != nil {
+			return nil, err
+		}
+
+		states[index] = res[i+1]
+	}
+
+	return states, nil
+}
+
+func (synchronizedCronTask *SynchronizedCronTask) shardStateKey(slot time.Time) string {
+	return synchronizedCronTask.keyspace.key(synchronizedCronTask.name, fmt.Sprintf("%d.shards", slot.UnixMilli()))
+}
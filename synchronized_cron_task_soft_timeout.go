@@ -0,0 +1,65 @@
+package crontask
+
+import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
+	"context"
+	"sync/atomic"
+)
+
+type softDeadlineContextKey struct{}
+
+// SoftDeadlineFromContext returns a channel, which is closed once the soft
+// timeout of the execution is reached - see crontask.SoftTimeout. Long-running
+// task functions should select on it, to checkpoint their work and return
+// before leadership is forcefully given up. If no soft timeout is configured
+// for the synchronized cron task, false is returned.
+func SoftDeadlineFromContext(ctx context.Context) (<-chan struct{}, bool) {
+	softDeadline, ok := ctx.Value(softDeadlineContextKey{}).(<-chan struct{})
+	return softDeadline, ok
+}
+
+func withSoftDeadline(ctx context.Context, softDeadline <-chan struct{}) context.Context {
+	return context.WithValue(ctx, softDeadlineContextKey{}, softDeadline)
+}
+
+// softDeadline closes its channel, once the soft timeout of a single
+// execution is reached.
+type softDeadline struct {
+	done    chan struct{}
+	timer   clock.Timer
+	reached int32
+}
+
+// startSoftDeadline starts the soft deadline of an execution, which begins
+// now. If no soft timeout is configured, nil is returned.
+func (synchronizedCronTask *SynchronizedCronTask) startSoftDeadline() *softDeadline {
+	if synchronizedCronTask.softTimeout <= 0 {
+		return nil
+	}
+
+	deadline := &softDeadline{done: make(chan struct{})}
+	deadline.timer = synchronizedCronTask.clock.AfterFunc(synchronizedCronTask.leadershipTimeout-synchronizedCronTask.softTimeout, func() {
+		atomic.StoreInt32(&deadline.reached, 1)
+		close(deadline.done)
+
+		synchronizedCronTask.logger.Warnf(
+			"Soft timeout reached for synchronized task %q - asking it to stop within %s, before leadership is forcefully given up",
+			synchronizedCronTask.name, synchronizedCronTask.softTimeout,
+		)
+	})
+
+	return deadline
+}
+
+// stop stops the soft deadline, if it was not reached yet.
+func (deadline *softDeadline) stop() {
+	if deadline != nil {
+		deadline.timer.Stop()
+	}
+}
+
+// wasReached returns true, if the soft deadline was reached.
+func (deadline *softDeadline) wasReached() bool {
+	return deadline != nil && atomic.LoadInt32(&deadline.reached) == 1
+}
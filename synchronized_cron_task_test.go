@@ -2,7 +2,10 @@ package crontask_test
 
 import (
 	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+	"github.com/kernle32dll/synchronized-cron-task/crontasktest"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
@@ -14,6 +17,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -30,11 +34,52 @@ func (executionTracker *ExecutionTracker) getFunc() crontask.TaskFunc {
 	}
 }
 
+// flakyClient fails all scripts - such as renewals of locks - while failing.
+type flakyClient struct {
+	*redis.Client
+
+	failing int32
+}
+
+func (client *flakyClient) setFailing(failing bool) {
+	if failing {
+		atomic.StoreInt32(&client.failing, 1)
+	} else {
+		atomic.StoreInt32(&client.failing, 0)
+	}
+}
+
+func (client *flakyClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	if atomic.LoadInt32(&client.failing) == 1 {
+		return redis.NewCmdResult(nil, errors.New("connection reset by peer"))
+	}
+
+	return client.Client.Eval(ctx, script, keys, args...)
+}
+
+func (client *flakyClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if atomic.LoadInt32(&client.failing) == 1 {
+		return redis.NewCmdResult(nil, errors.New("connection reset by peer"))
+	}
+
+	return client.Client.EvalSha(ctx, sha1, keys, args...)
+}
+
 func Test_SynchronizedCronTask(t *testing.T) {
 	t.Run("malformed-cron-expression", malformedCronExpressionTest)
 
 	t.Run("secondless-cron-expression", secondlessCronExpression)
 
+	t.Run("multiple-cron-expressions", multipleCronExpressionsTest)
+
+	t.Run("fake-clock-next-time", fakeClockNextTimeTest)
+
+	t.Run("clock-skew-disabled-by-default", clockSkewDisabledByDefaultTest)
+
+	t.Run("soft-timeout-failure", softTimeoutFailureTest)
+
+	t.Run("cluster-trigger-cluster-client", clusterTriggerClusterClientTest)
+
 	redisVersions := []string{
 		"5-alpine",
 		"6-alpine",
@@ -53,6 +98,36 @@ func Test_SynchronizedCronTask(t *testing.T) {
 			t.Run("stopped-execution-test", stoppedTests(version))
 
 			t.Run("error-in-execution-test", errorTest(version))
+
+			t.Run("dependency-test", dependencyTest(version))
+
+			t.Run("sharded-execution-test", shardedExecutionTest(version))
+
+			t.Run("cluster-trigger-test", clusterTriggerTest(version))
+
+			t.Run("cluster-trigger-late-delivery-test", clusterTriggerLateDeliveryTest(version))
+
+			t.Run("interval-schedule-test", intervalScheduleExecutionTest(version))
+
+			t.Run("keyspace-test", keyspaceTest(version))
+
+			t.Run("pause-test", pauseTest(version))
+
+			t.Run("task-state-test", taskStateTest(version))
+
+			t.Run("health-test", healthTest(version))
+
+			t.Run("soft-timeout-test", softTimeoutTest(version))
+
+			t.Run("cancellation-cause-test", cancellationCauseTest(version))
+
+			t.Run("heartbeat-retry-test", heartbeatRetryTest(version))
+
+			t.Run("completion-hold-test", completionHoldTest(version))
+
+			t.Run("clock-skew-test", clockSkewTest(version))
+
+			t.Run("fake-clock-test", fakeClockTest(version))
 		})
 	}
 }
@@ -247,66 +322,1257 @@ func errorTest(redisVersion string) func(t *testing.T) {
 	}
 }
 
-func secondlessCronExpression(t *testing.T) {
+func dependencyTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		upstreamTracker := &ExecutionTracker{}
+		upstream, err := crontask.NewSynchronizedCronTask(
+			client,
+			upstreamTracker.getFunc(),
+			crontask.TaskName("import"),
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		dependentTracker := &ExecutionTracker{}
+		dependent, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				defer wg.Done()
+				return dependentTracker.getFunc()(ctx, task)
+			},
+			crontask.TaskName("aggregate"),
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.DependsOn("import"),
+			crontask.DependencyPollInterval(10*time.Millisecond),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// when
+		go dependent.ExecuteNow()
+		time.Sleep(100 * time.Millisecond)
+
+		// then
+		if dependentTracker.count != 0 {
+			t.Fatal("dependent task was executed before its upstream task")
+		}
+
+		// when
+		upstream.ExecuteNow()
+
+		// Wait for the dependent task to pick up the completed slot
+		wg.Wait()
+
+		// then
+		if upstreamTracker.count != 1 || dependentTracker.count != 1 {
+			t.Fail()
+		}
+
+		logContains(
+			t, hook,
+
+			"is waiting for upstream tasks",
+		)
+	}
+}
+
+func shardedExecutionTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		executedShards := make(map[int]int)
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				shard, ok := crontask.ShardFromContext(ctx)
+				if !ok || shard.Count != 4 {
+					return fmt.Errorf("unexpected shard %v", shard)
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				executedShards[shard.Index]++
+
+				return nil
+			},
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.Shards(4),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// when
+		task.ExecuteNow()
+
+		// then
+		for index := 0; index < 4; index++ {
+			if executedShards[index] != 1 {
+				t.Errorf("expected shard %d to be executed once, but was executed %d times", index, executedShards[index])
+			}
+		}
+
+		logContains(
+			t, hook,
+
+			"Successfully executed shard 0 of synchronized task",
+			"Successfully executed shard 3 of synchronized task",
+			"Successfully executed synchronized task",
+		)
+	}
+}
+
+func clusterTriggerTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		executionTracker := &ExecutionTracker{retErr: errors.New("some error")}
+
+		for i := 0; i < 3; i++ {
+			task, err := crontask.NewSynchronizedCronTask(
+				client,
+				func(ctx context.Context, task crontask.Task) error {
+					mutex.Lock()
+					defer mutex.Unlock()
+					return executionTracker.getFunc()(ctx, task)
+				},
+				crontask.CronExpression("0 0 0 1 1 *"),
+				crontask.ClusterTrigger(client),
+				crontask.Logger(logger),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer task.Stop(context.Background())
+		}
+
+		// Wait for all subscriptions to be active
+		time.Sleep(100 * time.Millisecond)
+
+		// when
+		result, err := crontask.TriggerCluster(context.Background(), client, crontask.DefaultName)
+
+		// then
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if executionTracker.count != 1 {
+			t.Errorf("expected exactly one execution, but got %d", executionTracker.count)
+		}
+
+		if result.Instance == "" {
+			t.Error("expected executing instance to be reported")
+		}
+
+		if result.Error == nil || !strings.Contains(result.Error.Error(), "some error") {
+			t.Errorf("expected execution error to be reported, but got %v", result.Error)
+		}
+
+		logContains(
+			t, hook,
+
+			"Received cluster-wide trigger for synchronized task",
+		)
+	}
+}
+
+// On a redis cluster, PUBLISH only counts the subscribers of the node it is sent to.
+// Cluster clients send it to the master of the slot of the channel - which is where
+// they subscribe to the channel as well - so every subscribed instance is counted.
+func clusterTriggerClusterClientTest(t *testing.T) {
 	// given
+	nodes := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: nodes[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: nodes[1].Addr()}}},
+			}, nil
+		},
+	})
+	defer client.Close()
+
+	executions := int32(0)
+	for i := 0; i < 3; i++ {
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				atomic.AddInt32(&executions, 1)
+				return nil
+			},
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.TaskKeyspace(crontask.Keyspace{HashTags: true}),
+			crontask.ClusterTrigger(client),
+			crontask.Logger(nil),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+	}
+
+	// Wait for all subscriptions to be active
+	time.Sleep(100 * time.Millisecond)
+
 	// when
-	task, err := crontask.NewSynchronizedCronTask(
-		nil,
-		func(context.Context, crontask.Task) error { return nil },
-		crontask.CronExpression("0 0 1 1 *"),
-	)
+	result, err := crontask.Keyspace{HashTags: true}.TriggerCluster(context.Background(), client, crontask.DefaultName)
 
 	// then
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
 
-	if task == nil {
-		t.Error("Expected a task being returned, but none was")
+	if result.Instance == "" || result.Error != nil {
+		t.Errorf("expected successful execution to be reported, got %+v", result)
 	}
-}
 
-func malformedCronExpressionTest(t *testing.T) {
-	// when
-	task, err := crontask.NewSynchronizedCronTask(
-		nil, // We know the client is never used, thus we can use nil here safely
-		func(context.Context, crontask.Task) error { return nil },
-		crontask.CronExpression("aint-work"),
-	)
+	if count := atomic.LoadInt32(&executions); count != 1 {
+		t.Errorf("expected exactly one execution, but got %d", count)
+	}
 
-	// then
-	if err == nil {
-		t.Error("Expected error, but none occurred")
+	channel, subscribers := "{"+crontask.DefaultName+"}.trigger", 0
+	for _, node := range nodes {
+		subscribers += node.PubSubNumSub(channel)[channel]
 	}
 
-	if task != nil {
-		t.Error("Expected no task being returned, but was")
+	if subscribers != 3 {
+		t.Errorf("expected all instances to be subscribed on the master of the channel, got %d subscribers", subscribers)
 	}
 }
 
-func closeClient(t *testing.T, client *redis.Client, closer func(context.Context) error) {
-	if err := client.Close(); err != nil {
-		t.Logf("unexpected error shutting down redis client: %s", err)
-	}
+func clusterTriggerLateDeliveryTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
 
-	if err := closer(context.Background()); err != nil {
-		t.Logf("unexpected error shutting down redis container: %s", err)
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		executions := int32(0)
+		for i := 0; i < 2; i++ {
+			task, err := crontask.NewSynchronizedCronTask(
+				client,
+				func(ctx context.Context, task crontask.Task) error {
+					atomic.AddInt32(&executions, 1)
+					return nil
+				},
+				crontask.CronExpression("0 0 0 1 1 *"),
+				crontask.ClusterTrigger(client),
+				crontask.Logger(logger),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer task.Stop(context.Background())
+		}
+
+		// Capture the trigger, so it can be delivered again
+		pubSub := client.Subscribe(context.Background(), crontask.DefaultName+".trigger")
+		defer pubSub.Close()
+
+		if _, err := pubSub.Receive(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Wait for all subscriptions to be active
+		time.Sleep(100 * time.Millisecond)
+
+		if _, err := crontask.TriggerCluster(context.Background(), client, crontask.DefaultName); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		trigger, err := pubSub.ReceiveMessage(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// when - the instances receive the trigger after it was executed
+		if err := client.Publish(context.Background(), trigger.Channel, trigger.Payload).Err(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		// then
+		if count := atomic.LoadInt32(&executions); count != 1 {
+			t.Errorf("expected exactly one execution, but got %d", count)
+		}
+
+		logContains(
+			t, hook,
+
+			"was already handled by another instance",
+		)
 	}
 }
 
-func logContains(t *testing.T, hook *test.Hook, phrases ...string) {
-	for _, phrase := range phrases {
-		found := false
-		for _, entry := range hook.AllEntries() {
-			if strings.Contains(entry.Message, phrase) {
-				found = true
-				break
+func intervalScheduleExecutionTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		var starts, ends []time.Time
+
+		// when
+		for i := 0; i < 3; i++ {
+			task, err := crontask.NewSynchronizedCronTask(
+				client,
+				func(ctx context.Context, task crontask.Task) error {
+					mutex.Lock()
+					starts = append(starts, time.Now())
+					mutex.Unlock()
+
+					time.Sleep(500 * time.Millisecond)
+
+					mutex.Lock()
+					ends = append(ends, time.Now())
+					mutex.Unlock()
+					return nil
+				},
+				crontask.TaskSchedule(crontask.Interval(time.Second)),
+				crontask.Logger(logger),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
 			}
+			defer task.Stop(context.Background())
 		}
 
-		if !found {
-			t.Errorf("Log did not contain phrase %q", phrase)
-		}
+		time.Sleep(5 * time.Second)
+
+		// then
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if len(starts) < 2 {
+			t.Fatalf("expected at least two executions, but got %d", len(starts))
+		}
+
+		for i := 1; i < len(starts) && i <= len(ends); i++ {
+			// Redis only records milliseconds
+			if gap := starts[i].Sub(ends[i-1]); gap < time.Second-time.Millisecond {
+				t.Errorf("expected execution %d to start one interval after the previous one ended, but started after %s", i, gap)
+			}
+		}
+
+		logContains(
+			t, hook,
+
+			"is waiting",
+			"for its interval to pass",
+		)
+	}
+}
+
+func keyspaceTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		mutex := &sync.Mutex{}
+		executionTracker := &ExecutionTracker{}
+
+		var tasks []*crontask.SynchronizedCronTask
+		for _, namespace := range []string{"app-a", "app-b"} {
+			task, err := crontask.NewSynchronizedCronTask(
+				client,
+				func(ctx context.Context, task crontask.Task) error {
+					time.Sleep(200 * time.Millisecond)
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					return executionTracker.getFunc()(ctx, task)
+				},
+				crontask.CronExpression("0 0 0 1 1 *"),
+				crontask.TaskKeyspace(crontask.Keyspace{Namespace: namespace, HashTags: true}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer task.Stop(context.Background())
+
+			tasks = append(tasks, task)
+		}
+
+		// when
+		wg := &sync.WaitGroup{}
+		for _, task := range tasks {
+			wg.Add(1)
+			go func(task *crontask.SynchronizedCronTask) {
+				defer wg.Done()
+				task.ExecuteNow()
+			}(task)
+		}
+		wg.Wait()
+
+		// then
+		if executionTracker.count != 2 {
+			t.Errorf("expected tasks of both namespaces to be executed, but got %d executions", executionTracker.count)
+		}
+
+		for _, key := range []string{"app-a.{Default Synchronized Task}.completed", "app-b.{Default Synchronized Task}.completed"} {
+			if exists, err := client.Exists(context.Background(), key).Result(); err != nil || exists != 1 {
+				t.Errorf("expected key %q to exist, got %d (%v)", key, exists, err)
+			}
+		}
+	}
+}
+
+func pauseTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		executionTracker := &ExecutionTracker{}
+
+		if err := crontask.Pause(context.Background(), client, crontask.DefaultName); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return executionTracker.getFunc()(ctx, task)
+			},
+			crontask.CronExpression("* * * * * *"),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		time.Sleep(1500 * time.Millisecond)
+		mutex.Lock()
+		pausedCount := executionTracker.count
+		mutex.Unlock()
+
+		task.ExecuteNow()
+
+		if err := crontask.Resume(context.Background(), client, crontask.DefaultName); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		time.Sleep(1500 * time.Millisecond)
+
+		// then
+		if pausedCount != 0 {
+			t.Errorf("expected no executions while paused, but got %d", pausedCount)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		// One manual execution, and at least one firing after resuming
+		if executionTracker.count < 2 {
+			t.Errorf("expected manual execution and execution after resume, but got %d executions", executionTracker.count)
+		}
+
+		logContains(
+			t, hook,
+
+			"as it is paused",
+		)
+	}
+}
+
+func taskStateTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		registry, err := crontask.NewRegistry(client, crontask.RegistryInstanceID("some-instance"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer registry.Stop(context.Background())
+
+		release := make(chan struct{})
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				<-release
+				return nil
+			},
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.InstanceRegistry(registry),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		go task.ExecuteNow()
+		defer close(release)
+
+		// Wait for the task to be executing
+		time.Sleep(100 * time.Millisecond)
+
+		// when
+		locked, err := crontask.InspectTask(context.Background(), client, task.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		released, err := crontask.ForceRelease(context.Background(), client, task.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		unlocked, err := crontask.InspectTask(context.Background(), client, task.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// then
+		if !locked.Locked || locked.Holder != "some-instance" || locked.LockTTL <= 0 || locked.Paused() {
+			t.Errorf("unexpected state of locked task %+v", locked)
+		}
+
+		if !released {
+			t.Error("expected lock to be released")
+		}
+
+		if unlocked.Locked || unlocked.Holder != "" || unlocked.LockTTL != 0 {
+			t.Errorf("unexpected state of released task %+v", unlocked)
+		}
+	}
+}
+
+func softTimeoutTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				softDeadline, ok := crontask.SoftDeadlineFromContext(ctx)
+				if !ok {
+					return errors.New("no soft deadline in context")
+				}
+
+				select {
+				case <-softDeadline:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.LeadershipTimeout(2*time.Second),
+			crontask.SoftTimeout(1500*time.Millisecond),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		start := time.Now()
+		task.ExecuteNow()
+
+		// then
+		if elapsed := time.Since(start); elapsed >= 2*time.Second {
+			t.Errorf("expected task to stop before its leadership timeout, but it took %s", elapsed)
+		}
+
+		logContains(
+			t, hook,
+
+			"Soft timeout reached for synchronized task \"Default Synchronized Task\"",
+			"Synchronized task \"Default Synchronized Task\" stopped voluntarily after its soft timeout",
+			"Successfully executed synchronized task \"Default Synchronized Task\"",
+		)
+	}
+}
+
+func cancellationCauseTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		newTask := func(name string, causes chan<- error) *crontask.SynchronizedCronTask {
+			task, err := crontask.NewSynchronizedCronTask(
+				client,
+				func(ctx context.Context, task crontask.Task) error {
+					<-ctx.Done()
+					causes <- context.Cause(ctx)
+					return ctx.Err()
+				},
+				crontask.TaskName(name),
+				crontask.CronExpression("0 0 0 1 1 *"),
+				crontask.LeadershipTimeout(time.Second),
+				crontask.LockTimeout(time.Second),
+				crontask.LockHeartbeat(200*time.Millisecond),
+				crontask.Logger(logger),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			return task
+		}
+
+		timeoutCauses, lockLostCauses, stoppedCauses := make(chan error, 1), make(chan error, 1), make(chan error, 1)
+		timeoutTask := newTask("timeout-task", timeoutCauses)
+		defer timeoutTask.Stop(context.Background())
+		lockLostTask := newTask("lock-lost-task", lockLostCauses)
+		defer lockLostTask.Stop(context.Background())
+		stoppedTask := newTask("stopped-task", stoppedCauses)
+
+		// when
+		timeoutTask.ExecuteNow()
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			if _, err := crontask.ForceRelease(context.Background(), client, "lock-lost-task"); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+		lockLostTask.ExecuteNow()
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			stoppedTask.Stop(context.Background())
+		}()
+		stoppedTask.ExecuteNow()
+
+		// then
+		expectations := []struct {
+			causes   chan error
+			expected error
+		}{
+			{causes: timeoutCauses, expected: crontask.ErrLeadershipTimeout},
+			{causes: lockLostCauses, expected: crontask.ErrLockLost},
+			{causes: stoppedCauses, expected: crontask.ErrStopped},
+		}
+
+		for _, expectation := range expectations {
+			select {
+			case cause := <-expectation.causes:
+				if !errors.Is(cause, expectation.expected) {
+					t.Errorf("expected cause %q, got %v", expectation.expected, cause)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("expected cause %q, but the task function was not canceled", expectation.expected)
+			}
+		}
+
+		logContains(
+			t, hook,
+
+			"Forcefully giving up leadership for synchronized task \"timeout-task\" - timeout of 1s reached",
+			"Lost leadership for synchronized task \"lock-lost-task\" while executing",
+			"Aborted synchronized task \"stopped-task\", as it was stopped",
+		)
+	}
+}
+
+func heartbeatRetryTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		redisClient, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, redisClient, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		client := &flakyClient{Client: redisClient}
+
+		recovering, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				// Fails the renewals for half of the lock timeout
+				client.setFailing(true)
+				time.Sleep(time.Second)
+				client.setFailing(false)
+
+				time.Sleep(time.Second)
+				return ctx.Err()
+			},
+			crontask.TaskName("recovering-task"),
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.LockTimeout(2*time.Second),
+			crontask.LockHeartbeat(400*time.Millisecond),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer recovering.Stop(context.Background())
+
+		causes := make(chan error, 1)
+		failing, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				client.setFailing(true)
+				defer client.setFailing(false)
+
+				<-ctx.Done()
+				causes <- context.Cause(ctx)
+				return ctx.Err()
+			},
+			crontask.TaskName("failing-task"),
+			crontask.CronExpression("0 0 0 1 1 *"),
+			crontask.LockTimeout(2*time.Second),
+			crontask.LockHeartbeat(400*time.Millisecond),
+			crontask.HeartbeatMaxFailures(2),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer failing.Stop(context.Background())
+
+		// when
+		recovering.ExecuteNow()
+
+		start := time.Now()
+		failing.ExecuteNow()
+		elapsed := time.Since(start)
+
+		// then
+		if cause := <-causes; !errors.Is(cause, crontask.ErrLockLost) {
+			t.Errorf("expected cause %q, got %v", crontask.ErrLockLost, cause)
+		}
+
+		// The lock is lost after the first renewal, and a single retry
+		if elapsed >= time.Second {
+			t.Errorf("expected lock to be lost after two failed renewals, but it took %s", elapsed)
+		}
+
+		logContains(
+			t, hook,
+
+			"Failed to renew leadership for synchronized task \"recovering-task\" (attempt 1)",
+			"Successfully executed synchronized task \"recovering-task\"",
+			"Lost leadership for synchronized task \"failing-task\" while executing",
+		)
+	}
+}
+
+func completionHoldTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		executionTracker := &ExecutionTracker{}
+
+		// Another instance completed the upcoming slot already
+		heldSlot := time.Now().Truncate(time.Second).Add(time.Second)
+		if err := client.Set(context.Background(), crontask.DefaultName+".held", heldSlot.UnixMilli(), time.Minute).Err(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return executionTracker.getFunc()(ctx, task)
+			},
+			crontask.CronExpression("* * * * * *"),
+			crontask.CompletionHold(time.Minute),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		time.Sleep(time.Until(heldSlot.Add(500 * time.Millisecond)))
+		mutex.Lock()
+		heldCount := executionTracker.count
+		mutex.Unlock()
+
+		task.ExecuteNow()
+		mutex.Lock()
+		manualCount := executionTracker.count
+		mutex.Unlock()
+
+		time.Sleep(time.Second)
+		held, err := client.Get(context.Background(), crontask.DefaultName+".held").Int64()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// then
+		if heldCount != 0 {
+			t.Errorf("expected held slot to be skipped, but got %d executions", heldCount)
+		}
+
+		if manualCount != 1 {
+			t.Errorf("expected manual execution despite held slot, but got %d executions", manualCount)
+		}
+
+		if expected := heldSlot.Add(time.Second).UnixMilli(); held != expected {
+			t.Errorf("expected completion of slot %d to be held, got %d", expected, held)
+		}
+
+		logContains(
+			t, hook,
+
+			fmt.Sprintf("Synchronized task \"Default Synchronized Task\" already completed slot %s on another instance - ignoring", heldSlot.UTC()),
+		)
+	}
+}
+
+func clockSkewTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		mutex := &sync.Mutex{}
+		executionTracker := &ExecutionTracker{}
+		var measurements []crontask.ClockSkew
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return executionTracker.getFunc()(ctx, task)
+			},
+			crontask.CronExpression("* * * * * *"),
+			crontask.LockTimeout(time.Second),
+			crontask.LockHeartbeat(200*time.Millisecond),
+			crontask.ClockSkewInterval(200*time.Millisecond),
+			crontask.ClockSkewThreshold(1e-9),
+			crontask.ClockSkewHandler(func(name string, skew crontask.ClockSkew) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				measurements = append(measurements, skew)
+			}),
+			crontask.ClockSkewCorrection(true),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		time.Sleep(1500 * time.Millisecond)
+		skew, measured := task.ClockSkew()
+
+		// then
+		if !measured {
+			t.Fatal("expected clock skew to be measured")
+		}
+
+		// Redis runs on the same host, so the skew is negligible
+		if skew.Offset > time.Second || skew.Offset < -time.Second {
+			t.Errorf("expected negligible clock skew, got %s", skew.Offset)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if len(measurements) < 2 {
+			t.Errorf("expected periodic measurements to be handled, got %d", len(measurements))
+		}
+
+		if executionTracker.count < 1 {
+			t.Errorf("expected firings on the corrected clock, but got %d executions", executionTracker.count)
+		}
+
+		// The threshold of a nanosecond is exceeded by any skew
+		if skew.Offset != 0 {
+			logContains(
+				t, hook,
+
+				"from the redis server clock, which exceeds 1ns - executions of synchronized task \"Default Synchronized Task\" might not be exactly once",
+			)
+		}
+	}
+}
+
+func fakeClockTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		logger, hook := test.NewNullLogger()
+		logger.Level = logrus.TraceLevel
+
+		fakeClock := clock.NewFake(time.Date(2030, time.January, 1, 0, 0, 30, 0, time.UTC))
+
+		started := make(chan time.Time, 1)
+		causes := make(chan error, 1)
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(ctx context.Context, task crontask.Task) error {
+				slot, _ := crontask.SlotFromContext(ctx)
+				started <- slot
+
+				<-ctx.Done()
+				causes <- context.Cause(ctx)
+				return nil
+			},
+			crontask.CronExpression("0 * * * * *"),
+			crontask.LeadershipTimeout(5*time.Second),
+			crontask.LockTimeout(5*time.Second),
+			crontask.LockHeartbeat(time.Second),
+			crontask.ClockSkewInterval(0),
+			crontask.Clock(fakeClock),
+			crontask.Logger(logger),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// when
+		if err := fakeClock.BlockUntil(ctx, 1); err != nil {
+			t.Fatalf("cron never waited for its first firing: %s", err)
+		}
+
+		fakeClock.Advance(30 * time.Second)
+
+		var slot time.Time
+		select {
+		case slot = <-started:
+		case <-ctx.Done():
+			t.Fatal("expected the cron to fire, after the fake clock was advanced")
+		}
+
+		// The leadership deadline, the heartbeat and the next firing are waiting
+		if err := fakeClock.BlockUntil(ctx, 3); err != nil {
+			t.Fatalf("execution never waited on the fake clock: %s", err)
+		}
+
+		// Heartbeats are driven by the fake clock, while the lock still expires in real time
+		for i := 0; i < 4; i++ {
+			fakeClock.Advance(time.Second)
+			awaitLog(t, hook, "Renewed leadership lock for long running synchronized task", i+1)
+		}
+
+		fakeClock.Advance(time.Second)
+
+		// then
+		if expected := time.Date(2030, time.January, 1, 0, 1, 0, 0, time.UTC); !slot.Equal(expected) {
+			t.Errorf("expected firing for slot %s, got %s", expected, slot)
+		}
+
+		select {
+		case cause := <-causes:
+			if !errors.Is(cause, crontask.ErrLeadershipTimeout) {
+				t.Errorf("expected leadership timeout on the fake clock, got %v", cause)
+			}
+		case <-ctx.Done():
+			t.Fatal("expected execution to be canceled, after the fake clock passed the leadership timeout")
+		}
+
+		awaitLog(t, hook, "Forcefully giving up leadership for synchronized task \"Default Synchronized Task\" - timeout of 5s reached", 1)
+	}
+}
+
+func healthTest(redisVersion string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// given
+		client, closer := getRedisClient(t, redisVersion)
+		defer closeClient(t, client, closer)
+
+		task, err := crontask.NewSynchronizedCronTask(
+			client,
+			func(context.Context, crontask.Task) error { return nil },
+			crontask.CronExpression("0 0 0 1 1 *"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer task.Stop(context.Background())
+
+		// when
+		before := task.Health(context.Background())
+		task.ExecuteNow()
+		after := task.Health(context.Background())
+
+		// then
+		if !before.BackendReachable || !before.Ready || !before.LastElection.IsZero() {
+			t.Errorf("expected ready task without elections, got %+v", before)
+		}
+
+		if !after.Ready || after.LastElection.IsZero() || after.LastLeadership.IsZero() || after.ConsecutiveFailures != 0 {
+			t.Errorf("expected ready task with won election, got %+v", after)
+		}
+	}
+}
+
+func secondlessCronExpression(t *testing.T) {
+	// given
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("0 0 1 1 *"),
+	)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if task == nil {
+		t.Error("Expected a task being returned, but none was")
+	}
+}
+
+func multipleCronExpressionsTest(t *testing.T) {
+	// given
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpressions("0 0 0 1 1 *", "0 0 0 * * *", "@midnight"),
+	)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	if nextTime := task.NextTime(); time.Until(nextTime) > 24*time.Hour {
+		t.Errorf("Expected next time to be the earliest of all expressions, but was %s", nextTime)
+	}
+
+	// when
+	_, err = crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpressions("0 0 0 * * *", "aint-work"),
+	)
+
+	// then
+	if err == nil {
+		t.Error("Expected error for malformed cron expression, but none occurred")
+	}
+}
+
+// Tests that the clock skew is only measured, if it is used.
+func clockSkewDisabledByDefaultTest(t *testing.T) {
+	// given
+	harness := crontasktest.New(t)
+	noop := func(context.Context, crontask.Task) error { return nil }
+
+	defaultTask := harness.StartInstance(noop, crontask.TaskName("default")).Task
+	handlerTask := harness.StartInstance(noop,
+		crontask.TaskName("handler"),
+		crontask.ClockSkewHandler(func(string, crontask.ClockSkew) {}),
+	).Task
+
+	// when
+	harness.Settle()
+
+	// then
+	if _, ok := defaultTask.ClockSkew(); ok {
+		t.Error("expected clock skew not to be measured by default")
+	}
+
+	if _, ok := handlerTask.ClockSkew(); !ok {
+		t.Error("expected clock skew to be measured, if a clock skew handler is configured")
+	}
+}
+
+// Tests that executions failing after their soft timeout are not logged as stopped voluntarily.
+func softTimeoutFailureTest(t *testing.T) {
+	// given
+	logger, hook := test.NewNullLogger()
+	logger.Level = logrus.TraceLevel
+
+	harness := crontasktest.New(t)
+	harness.StartInstance(
+		func(ctx context.Context, task crontask.Task) error {
+			softDeadline, _ := crontask.SoftDeadlineFromContext(ctx)
+			<-softDeadline
+			return errors.New("checkpoint failed")
+		},
+		crontask.CronExpression("0 * * * * *"),
+		crontask.LeadershipTimeout(30*time.Second),
+		crontask.SoftTimeout(10*time.Second),
+		crontask.Logger(logger),
+	)
+
+	// when - the soft deadline is reached 10s before the leadership timeout
+	harness.Advance(time.Minute + 20*time.Second)
+
+	// then
+	logContains(
+		t, hook,
+
+		"Soft timeout reached for synchronized task \"Default Synchronized Task\"",
+		"checkpoint failed",
+	)
+
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, "stopped voluntarily") {
+			t.Errorf("expected failed execution not to be logged as stopped voluntarily, got %q", entry.Message)
+		}
+	}
+}
+
+func fakeClockNextTimeTest(t *testing.T) {
+	// given
+	fakeClock := clock.NewFake(time.Date(2030, time.January, 1, 0, 0, 30, 0, time.UTC))
+
+	task, err := crontask.NewSynchronizedCronTask(
+		nil, // We know the client is never used, thus we can use nil here safely
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("0 * * * * *"),
+		crontask.Clock(fakeClock),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	// when
+	nextTime := task.NextTime()
+
+	// then
+	if expected := time.Date(2030, time.January, 1, 0, 1, 0, 0, time.UTC); !nextTime.Equal(expected) {
+		t.Errorf("Expected next time %s on the fake clock, but was %s", expected, nextTime)
+	}
+}
+
+func malformedCronExpressionTest(t *testing.T) {
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil, // We know the client is never used, thus we can use nil here safely
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("aint-work"),
+	)
+
+	// then
+	if err == nil {
+		t.Error("Expected error, but none occurred")
+	}
+
+	if task != nil {
+		t.Error("Expected no task being returned, but was")
+	}
+}
+
+func closeClient(t *testing.T, client *redis.Client, closer func(context.Context) error) {
+	if err := client.Close(); err != nil {
+		t.Logf("unexpected error shutting down redis client: %s", err)
+	}
+
+	if err := closer(context.Background()); err != nil {
+		t.Logf("unexpected error shutting down redis container: %s", err)
+	}
+}
+
+func logContains(t *testing.T, hook *test.Hook, phrases ...string) {
+	for _, phrase := range phrases {
+		found := false
+		for _, entry := range hook.AllEntries() {
+			if strings.Contains(entry.Message, phrase) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("Log did not contain phrase %q", phrase)
+		}
+	}
+}
+
+// awaitLog waits for the given phrase to be logged at least count times, as
+// executions driven by a fake clock still run in their own goroutines.
+func awaitLog(t *testing.T, hook *test.Hook, phrase string, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		found := 0
+		for _, entry := range hook.AllEntries() {
+			if strings.Contains(entry.Message, phrase) {
+				found++
+			}
+		}
+
+		if found >= count {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Log did not contain phrase %q %d times, got %d", phrase, count, found)
+		}
+
+		time.Sleep(10 * time.Millisecond)
 	}
 }
 
@@ -0,0 +1,243 @@
+package crontask
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// overlapSamples is the number of upcoming firings inspected, to
+// determine the shortest time span between firings of a schedule.
+const overlapSamples = 32
+
+// OptionError describes a single invalid option.
+type OptionError struct {
+	Option string
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("%s %s", e.Option, e.Reason)
+}
+
+// ValidationError aggregates all invalid options, found while validating
+// the options of a synchronized cron task, a singleton or a registry.
+type ValidationError struct {
+	Errors []OptionError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		reasons[i] = err.Error()
+	}
+
+	return fmt.Sprintf("invalid task options: %s", strings.Join(reasons, "; "))
+}
+
+func (e *ValidationError) add(option string, reason string, args ...interface{}) {
+	e.Errors = append(e.Errors, OptionError{
+		Option: option,
+		Reason: fmt.Sprintf(reason, args...),
+	})
+}
+
+// Validate checks the options for invalid values and combinations. All invalid
+// options are aggregated into a single *ValidationError. If all options are
+// valid, nil is returned.
+func (options *TaskOptions) Validate() error {
+	validation := &ValidationError{}
+
+	if options.Name == "" {
+		validation.add("Name", "must not be empty, as it identifies the lock of the task")
+	}
+
+	if options.LeadershipTimeout <= 0 {
+		validation.add("LeadershipTimeout", "must be positive, got %s", options.LeadershipTimeout)
+	}
+
+	if options.SoftTimeout < 0 {
+		validation.add("SoftTimeout", "must not be negative, got %s", options.SoftTimeout)
+	} else if options.LeadershipTimeout > 0 && options.SoftTimeout >= options.LeadershipTimeout {
+		validation.add(
+			"SoftTimeout", "must be shorter than the LeadershipTimeout of %s, or the task is asked to stop before it starts - got %s",
+			options.LeadershipTimeout, options.SoftTimeout,
+		)
+	}
+
+	options.validateLock(validation)
+
+	if options.Shards < 0 {
+		validation.add("Shards", "must not be negative, got %d", options.Shards)
+	}
+
+	if options.CompletionHold < 0 {
+		validation.add("CompletionHold", "must not be negative, got %s", options.CompletionHold)
+	} else if options.CompletionHold > 0 && options.Shards > 1 {
+		validation.add("CompletionHold", "must be zero, if the task is sharded - shards are tracked per slot already")
+	}
+
+	if options.HealthFailureThreshold < 0 {
+		validation.add("HealthFailureThreshold", "must not be negative, got %d", options.HealthFailureThreshold)
+	}
+
+	if options.ClockSkewInterval < 0 {
+		validation.add("ClockSkewInterval", "must not be negative, got %s", options.ClockSkewInterval)
+	}
+
+	if options.ClockSkewThreshold < 0 {
+		validation.add("ClockSkewThreshold", "must not be negative, got %g", options.ClockSkewThreshold)
+	}
+
+	for _, dependency := range options.Dependencies {
+		if dependency == options.Name {
+			validation.add("Dependencies", "must not contain the task itself")
+		}
+	}
+
+	if len(options.Dependencies) > 0 && options.DependencyPollInterval <= 0 {
+		validation.add("DependencyPollInterval", "must be positive if there are dependencies, got %s", options.DependencyPollInterval)
+	}
+
+	schedule, err := parseSchedule(options)
+	switch {
+	case err != nil && len(options.CronExpressions) > 0:
+		validation.add("CronExpressions", "must be valid: %s", err)
+	case err != nil:
+		validation.add("CronExpression", "must be valid: %s", err)
+	}
+
+	if _, ok := schedule.(*intervalSchedule); ok && options.Shards > 1 {
+		validation.add("Schedule", "must not be an interval schedule, if the task is sharded")
+	}
+
+	if len(validation.Errors) > 0 {
+		return validation
+	}
+
+	return nil
+}
+
+// validateSingleton checks the options used by a singleton - see
+// NewSingletonWithOptions - just like Validate does for a task.
+func (options *TaskOptions) validateSingleton() error {
+	validation := &ValidationError{}
+
+	if options.Name == "" {
+		validation.add("Name", "must not be empty, as it identifies the lock of the singleton")
+	}
+
+	options.validateLock(validation)
+
+	if len(validation.Errors) > 0 {
+		return validation
+	}
+
+	return nil
+}
+
+// validateRegistry checks the options used by an instance registry - see
+// NewRegistryWithOptions - just like Validate does for a task.
+func (options *RegistryOptions) validateRegistry() error {
+	validation := &ValidationError{}
+
+	if options.Heartbeat <= 0 {
+		validation.add("Heartbeat", "must be positive, got %s", options.Heartbeat)
+	}
+
+	if options.TTL <= 0 {
+		validation.add("TTL", "must be positive, got %s", options.TTL)
+	} else if options.Heartbeat > 0 && options.TTL <= options.Heartbeat {
+		validation.add(
+			"TTL", "must be longer than the Heartbeat of %s, or the instance expires before renewing its registration - got %s",
+			options.Heartbeat, options.TTL,
+		)
+	}
+
+	if len(validation.Errors) > 0 {
+		return validation
+	}
+
+	return nil
+}
+
+// validateLock checks the options of the lock, which tasks and singletons share.
+func (options *TaskOptions) validateLock(validation *ValidationError) {
+	if options.LockTimeout <= 0 {
+		validation.add("LockTimeout", "must be positive, got %s", options.LockTimeout)
+	}
+
+	if options.LockHeartbeat <= 0 {
+		validation.add("LockHeartbeat", "must be positive, got %s", options.LockHeartbeat)
+	} else if options.LockTimeout > 0 && options.LockHeartbeat >= options.LockTimeout {
+		validation.add(
+			"LockHeartbeat", "must be shorter than the LockTimeout of %s, or the lock expires before it is renewed - got %s",
+			options.LockTimeout, options.LockHeartbeat,
+		)
+	}
+
+	if options.HeartbeatMaxFailures < 0 {
+		validation.add("HeartbeatMaxFailures", "must not be negative, got %d", options.HeartbeatMaxFailures)
+	}
+
+	if options.HeartbeatSafetyMargin < 0 {
+		validation.add("HeartbeatSafetyMargin", "must not be negative, got %s", options.HeartbeatSafetyMargin)
+	} else if options.LockTimeout > 0 && options.HeartbeatSafetyMargin >= options.LockTimeout {
+		validation.add(
+			"HeartbeatSafetyMargin", "must be shorter than the LockTimeout of %s, or the lock is lost upon the first failed renewal - got %s",
+			options.LockTimeout, options.HeartbeatSafetyMargin,
+		)
+	}
+}
+
+// warnings returns descriptions of all options, which are valid but suspicious.
+// Upcoming firings of the schedule are inspected from the given point in time.
+func (options *TaskOptions) warnings(schedule Schedule, now time.Time) []string {
+	var warnings []string
+
+	if options.LockHeartbeat > options.LockTimeout/2 {
+		warnings = append(warnings, fmt.Sprintf(
+			"the LockHeartbeat of %s exceeds half the LockTimeout of %s, so little time is left to retry a failed renewal before the lock expires",
+			options.LockHeartbeat, options.LockTimeout,
+		))
+	}
+
+	// Intervals are measured from the end of the last run, so runs never overlap
+	if _, ok := schedule.(*intervalSchedule); ok {
+		return warnings
+	}
+
+	if shortest, ok := shortestPeriod(schedule, now); ok && options.LeadershipTimeout > shortest {
+		warnings = append(warnings, fmt.Sprintf(
+			"the LeadershipTimeout of %s exceeds the %s between firings, so runs can overlap their own schedule - overlapping firings are skipped",
+			options.LeadershipTimeout, shortest,
+		))
+	}
+
+	return warnings
+}
+
+// shortestPeriod returns the shortest time span between the upcoming
+// firings of the schedule. If it fires less than twice, false is returned.
+func shortestPeriod(schedule Schedule, from time.Time) (time.Duration, bool) {
+	previous := schedule.Next(from)
+	if previous.IsZero() {
+		return 0, false
+	}
+
+	var shortest time.Duration
+	for i := 0; i < overlapSamples; i++ {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+
+		if period := next.Sub(previous); shortest == 0 || period < shortest {
+			shortest = period
+		}
+
+		previous = next
+	}
+
+	return shortest, shortest > 0
+}
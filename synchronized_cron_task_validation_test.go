@@ -0,0 +1,130 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_TaskOptions_Validate(t *testing.T) {
+	t.Run("valid-options", validOptionsTest)
+
+	t.Run("aggregated-errors", aggregatedValidationErrorsTest)
+
+	t.Run("construction", validationOnConstructionTest)
+
+	t.Run("warnings", validationWarningsTest)
+}
+
+func validOptionsTest(t *testing.T) {
+	// given
+	options := &crontask.TaskOptions{
+		Name:              "some-task",
+		CronExpression:    "0 * * * * *",
+		LeadershipTimeout: 30 * time.Second,
+		LockTimeout:       5 * time.Second,
+		LockHeartbeat:     time.Second,
+	}
+
+	// when
+	err := options.Validate()
+
+	// then
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func aggregatedValidationErrorsTest(t *testing.T) {
+	// given
+	options := &crontask.TaskOptions{
+		Name:              "",
+		CronExpression:    "aint-work",
+		LeadershipTimeout: 30 * time.Second,
+		SoftTimeout:       time.Minute,
+		LockTimeout:       time.Second,
+		LockHeartbeat:     5 * time.Second,
+		Shards:            -1,
+		CompletionHold:    -time.Second,
+
+		HeartbeatSafetyMargin: time.Second,
+
+		HealthFailureThreshold: -1,
+		ClockSkewInterval:      -time.Second,
+	}
+
+	// when
+	err := options.Validate()
+
+	// then
+	var validationErr *crontask.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	expected := []string{"Name", "SoftTimeout", "LockHeartbeat", "HeartbeatSafetyMargin", "Shards", "CompletionHold", "HealthFailureThreshold", "ClockSkewInterval", "CronExpression"}
+	if len(validationErr.Errors) != len(expected) {
+		t.Fatalf("expected %d invalid options, got %d: %s", len(expected), len(validationErr.Errors), err)
+	}
+
+	for i, option := range expected {
+		if validationErr.Errors[i].Option != option {
+			t.Errorf("expected invalid option %d to be %s, got %s", i, option, validationErr.Errors[i].Option)
+		}
+	}
+}
+
+func validationOnConstructionTest(t *testing.T) {
+	// when
+	task, err := crontask.NewSynchronizedCronTaskWithOptions(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		&crontask.TaskOptions{Name: "some-task", CronExpression: "0 * * * * *"},
+	)
+
+	// then
+	var validationErr *crontask.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	if task != nil {
+		t.Error("Expected no task being returned, but was")
+	}
+}
+
+func validationWarningsTest(t *testing.T) {
+	// given
+	logger, hook := test.NewNullLogger()
+	logger.Level = logrus.TraceLevel
+
+	// when
+	task, err := crontask.NewSynchronizedCronTask(
+		nil,
+		func(context.Context, crontask.Task) error { return nil },
+		crontask.CronExpression("*/10 * * * * *"),
+		crontask.LeadershipTimeout(time.Minute),
+		crontask.LockTimeout(5*time.Second),
+		crontask.LockHeartbeat(4*time.Second),
+		crontask.Logger(logger),
+	)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer task.Stop(context.Background())
+
+	logContains(
+		t, hook,
+
+		"so little time is left to retry a failed renewal before the lock expires",
+		"so runs can overlap their own schedule",
+	)
+}
@@ -0,0 +1,207 @@
+package crontask
+
+import (
+	"github.com/bsm/redislock"
+	"github.com/go-redis/redis/v8"
+
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	luaPause = redis.NewScript(`
+		redis.replicate_commands()
+		local now = redis.call("time")
+		return redis.call("set", KEYS[1], string.format("%.0f", now[1] * 1000 + math.floor(now[2] / 1000)))
+	`)
+	luaResume       = redis.NewScript(`return redis.call("del", KEYS[1])`)
+	luaForceRelease = redis.NewScript(`return redis.call("del", KEYS[1])`)
+	luaPausedSince  = redis.NewScript(`return redis.call("get", KEYS[1]) or "0"`)
+	luaTaskState    = redis.NewScript(`
+		return {
+			redis.call("get", KEYS[1]) or "",
+			redis.call("pttl", KEYS[1]),
+			redis.call("get", KEYS[2]) or "0",
+			redis.call("get", KEYS[3]) or ""
+		}
+	`)
+)
+
+// errPaused signals that a firing was skipped, as the task is paused.
+var errPaused = errors.New("task is paused")
+
+// TaskState describes the state of a synchronized cron task in redis.
+type TaskState struct {
+	Name string `json:"name"`
+
+	// Locked is true, if an instance currently holds the lock of the task.
+	Locked bool `json:"locked"`
+
+	// Holder is the id of the instance holding the lock. It is empty,
+	// unless the holding instance is registered in a registry.
+	Holder string `json:"holder"`
+
+	// LockTTL is the remaining time, until the lock expires.
+	LockTTL time.Duration `json:"lockTTL"`
+
+	// PausedSince is the time the task was paused, or zero if it is not paused.
+	PausedSince time.Time `json:"pausedSince"`
+}
+
+// Paused returns true, if the task is paused.
+func (state TaskState) Paused() bool {
+	return !state.PausedSince.IsZero()
+}
+
+// Pause pauses the synchronized cron task with the given name on all instances.
+// Firings of paused tasks are skipped, until the task is resumed. Manual
+// executions - via ExecuteNow or TriggerCluster - are still honored. The
+// time of pausing is taken from the clock of the redis server.
+//
+// Tasks within a custom keyspace must be paused via Keyspace.Pause.
+func Pause(ctx context.Context, client redislock.RedisClient, name string) error {
+	return Keyspace{}.Pause(ctx, client, name)
+}
+
+// Pause pauses the synchronized cron task with the given name
+// within the keyspace. See crontask.Pause for details.
+func (keyspace Keyspace) Pause(ctx context.Context, client redislock.RedisClient, name string) error {
+	return luaPause.Run(ctx, client, []string{keyspace.key(name, "paused")}).Err()
+}
+
+// Resume resumes the paused synchronized cron task with the given name. Resuming
+// a task, which is not paused, has no effect.
+//
+// Tasks within a custom keyspace must be resumed via Keyspace.Resume.
+func Resume(ctx context.Context, client redislock.RedisClient, name string) error {
+	return Keyspace{}.Resume(ctx, client, name)
+}
+
+// Resume resumes the synchronized cron task with the given name
+// within the keyspace. See crontask.Resume for details.
+func (keyspace Keyspace) Resume(ctx context.Context, client redislock.RedisClient, name string) error {
+	return luaResume.Run(ctx, client, []string{keyspace.key(name, "paused")}).Err()
+}
+
+// ForceRelease releases the lock of the synchronized cron task with the given
+// name, regardless of the instance holding it. This is meant for recovering from
+// stuck locks - the holding instance keeps running its execution, if still alive,
+// so another instance might execute the task concurrently. Locks of shards are
+// not released. Returns true, if the lock was held.
+//
+// Locks within a custom keyspace must be released via Keyspace.ForceRelease.
+func ForceRelease(ctx context.Context, client redislock.RedisClient, name string) (bool, error) {
+	return Keyspace{}.ForceRelease(ctx, client, name)
+}
+
+// ForceRelease releases the lock of the synchronized cron task with the given
+// name within the keyspace. See crontask.ForceRelease for details.
+func (keyspace Keyspace) ForceRelease(ctx context.Context, client redislock.RedisClient, name string) (bool, error) {
+	lockKey := keyspace.key(name, "lock")
+
+	released, err := luaForceRelease.Run(ctx, client, []string{lockKey}).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	if err := luaForceRelease.Run(ctx, client, []string{holderKey(lockKey)}).Err(); err != nil {
+		return false, err
+	}
+
+	return released > 0, nil
+}
+
+// InspectTask returns the state of the synchronized cron task with the given name.
+//
+// Tasks within a custom keyspace must be inspected via Keyspace.InspectTask.
+func InspectTask(ctx context.Context, client redislock.RedisClient, name string) (TaskState, error) {
+	return Keyspace{}.InspectTask(ctx, client, name)
+}
+
+// InspectTask returns the state of the synchronized cron task with the
+// given name within the keyspace. See crontask.InspectTask for details.
+//
+// The lock, its holder and the pause state are read by a single script, so on
+// a redis cluster the keyspace must use hash tags - see Keyspace.HashTags.
+func (keyspace Keyspace) InspectTask(ctx context.Context, client redislock.RedisClient, name string) (TaskState, error) {
+	lockKey := keyspace.key(name, "lock")
+
+	res, err := luaTaskState.Run(ctx, client, []string{lockKey, keyspace.key(name, "paused"), holderKey(lockKey)}).Slice()
+	if err != nil {
+		return TaskState{}, err
+	}
+
+	value, _ := res[0].(string)
+	ttl, _ := res[1].(int64)
+	pausedSince, _ := res[2].(string)
+	holder, _ := res[3].(string)
+
+	state := TaskState{
+		Name:   name,
+		Locked: value != "",
+	}
+
+	// A holder might outlive a force released lock for a moment
+	if state.Locked {
+		state.Holder = holder
+	}
+
+	if ttl > 0 {
+		state.LockTTL = time.Duration(ttl) * time.Millisecond
+	}
+
+	state.PausedSince, err = parsePausedSince(pausedSince)
+	if err != nil {
+		return TaskState{}, err
+	}
+
+	return state, nil
+}
+
+// parsePausedSince parses the value of the paused key, which
+// holds the unix time in milliseconds the task was paused.
+func parsePausedSince(value string) (time.Time, error) {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if millis <= 0 {
+		return time.Time{}, nil
+	}
+
+	return time.UnixMilli(millis).UTC(), nil
+}
+
+// paused returns true, if the task is paused. Only the paused key is read,
+// so no hash tags are required on a redis cluster.
+func (elector *elector) paused(ctx context.Context) (bool, error) {
+	value, err := luaPausedSince.Run(ctx, elector.client, []string{elector.keyspace.key(elector.name, "paused")}).Text()
+	if err != nil {
+		return false, err
+	}
+
+	pausedSince, err := parsePausedSince(value)
+	if err != nil {
+		return false, err
+	}
+
+	return !pausedSince.IsZero(), nil
+}
+
+// Pause pauses the task on all instances. See crontask.Pause for details.
+func (synchronizedCronTask *SynchronizedCronTask) Pause(ctx context.Context) error {
+	return synchronizedCronTask.keyspace.Pause(ctx, synchronizedCronTask.client, synchronizedCronTask.name)
+}
+
+// Resume resumes the task on all instances. See crontask.Resume for details.
+func (synchronizedCronTask *SynchronizedCronTask) Resume(ctx context.Context) error {
+	return synchronizedCronTask.keyspace.Resume(ctx, synchronizedCronTask.client, synchronizedCronTask.name)
+}
+
+// State returns the state of the task in redis. See crontask.InspectTask for details.
+func (synchronizedCronTask *SynchronizedCronTask) State(ctx context.Context) (TaskState, error) {
+	return synchronizedCronTask.keyspace.InspectTask(ctx, synchronizedCronTask.client, synchronizedCronTask.name)
+}
@@ -0,0 +1,115 @@
+package crontask
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// TaskResult is the structured result of a single execution of a task
+// function, as reported via SetSummary, AddMetric and SetOutput.
+type TaskResult struct {
+	// Summary is a human readable summary, e.g. "processed 1234 rows".
+	Summary string `json:"summary,omitempty"`
+
+	// Metrics are named counts, e.g. the number of processed rows.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Output is arbitrary JSON.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// IsZero returns true, if nothing was reported.
+func (result TaskResult) IsZero() bool {
+	return result.Summary == "" && len(result.Metrics) == 0 && len(result.Output) == 0
+}
+
+type resultContextKey struct{}
+
+// resultRecorder records the result reported by a single execution.
+type resultRecorder struct {
+	mutex  sync.Mutex
+	result TaskResult
+}
+
+// WithResultRecorder returns a context, which records the result reported by
+// task functions executed with it. If the given context records results
+// already, it is returned as is - so nested wrappers observe the same result.
+func WithResultRecorder(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(resultContextKey{}).(*resultRecorder); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, resultContextKey{}, &resultRecorder{})
+}
+
+// ResultFromContext returns the result reported so far with the given context.
+// If the context does not record results - see WithResultRecorder - false is
+// returned.
+func ResultFromContext(ctx context.Context) (TaskResult, bool) {
+	recorder, ok := ctx.Value(resultContextKey{}).(*resultRecorder)
+	if !ok {
+		return TaskResult{}, false
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	result := recorder.result
+	if result.Metrics != nil {
+		result.Metrics = make(map[string]float64, len(recorder.result.Metrics))
+		for name, value := range recorder.result.Metrics {
+			result.Metrics[name] = value
+		}
+	}
+
+	return result, true
+}
+
+// SetSummary sets the summary of the result of the current execution. Like
+// all reporting functions, this is a no-op if the context does not record
+// results - e.g. as the task function is not wrapped by a time keeper.
+func SetSummary(ctx context.Context, summary string) {
+	recordResult(ctx, func(result *TaskResult) {
+		result.Summary = summary
+	})
+}
+
+// AddMetric adds the given value to the named metric of the result of the
+// current execution. Metrics start at zero.
+func AddMetric(ctx context.Context, name string, value float64) {
+	recordResult(ctx, func(result *TaskResult) {
+		if result.Metrics == nil {
+			result.Metrics = map[string]float64{}
+		}
+
+		result.Metrics[name] += value
+	})
+}
+
+// SetOutput sets the output of the result of the current execution, which is
+// marshalled to JSON. An error is returned, if marshalling fails.
+func SetOutput(ctx context.Context, output interface{}) error {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+
+	recordResult(ctx, func(result *TaskResult) {
+		result.Output = data
+	})
+
+	return nil
+}
+
+func recordResult(ctx context.Context, record func(result *TaskResult)) {
+	recorder, ok := ctx.Value(resultContextKey{}).(*resultRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	record(&recorder.result)
+}
@@ -0,0 +1,94 @@
+package crontask_test
+
+import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Tests that results reported with a recording context are returned.
+func Test_TaskResult_Record(t *testing.T) {
+	// given
+	ctx := crontask.WithResultRecorder(context.Background())
+
+	// when
+	crontask.SetSummary(ctx, "processed 1234 rows")
+	crontask.AddMetric(ctx, "rows", 1000)
+	crontask.AddMetric(ctx, "rows", 234)
+	err := crontask.SetOutput(ctx, map[string]string{"table": "users"})
+
+	// then
+	if err != nil {
+		t.Errorf("unexpected error, got %s", err)
+	}
+
+	result, ok := crontask.ResultFromContext(ctx)
+	if !ok {
+		t.Fatal("expected context to record results")
+	}
+
+	expected := crontask.TaskResult{
+		Summary: "processed 1234 rows",
+		Metrics: map[string]float64{"rows": 1234},
+		Output:  json.RawMessage(`{"table":"users"}`),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result, got %+v, wanted %+v", result, expected)
+	}
+}
+
+// Tests that reporting results is a no-op, if the context does not record them.
+func Test_TaskResult_NoRecorder(t *testing.T) {
+	// given
+	ctx := context.Background()
+
+	// when
+	crontask.SetSummary(ctx, "processed 1234 rows")
+	crontask.AddMetric(ctx, "rows", 1234)
+	err := crontask.SetOutput(ctx, "output")
+
+	// then
+	if err != nil {
+		t.Errorf("unexpected error, got %s", err)
+	}
+
+	if result, ok := crontask.ResultFromContext(ctx); ok || !result.IsZero() {
+		t.Errorf("expected no result to be recorded, got %+v", result)
+	}
+}
+
+// Tests that nested recording contexts share the same result.
+func Test_TaskResult_NestedRecorder(t *testing.T) {
+	// given
+	outer := crontask.WithResultRecorder(context.Background())
+	inner := crontask.WithResultRecorder(outer)
+
+	// when
+	crontask.SetSummary(inner, "processed 1234 rows")
+
+	// then
+	if result, _ := crontask.ResultFromContext(outer); result.Summary != "processed 1234 rows" {
+		t.Errorf("expected outer context to observe the result, got %+v", result)
+	}
+}
+
+// Tests that outputs which cannot be marshalled are rejected.
+func Test_TaskResult_SetOutput_error(t *testing.T) {
+	// given
+	ctx := crontask.WithResultRecorder(context.Background())
+
+	// when
+	err := crontask.SetOutput(ctx, make(chan int))
+
+	// then
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	if result, _ := crontask.ResultFromContext(ctx); !result.IsZero() {
+		t.Errorf("expected no output to be recorded, got %+v", result)
+	}
+}
@@ -2,16 +2,24 @@ package timekeeper
 
 import (
 	"github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
+// ErrKeysNotColocated is returned upon creating a time keeper for a redis cluster
+// or ring, if the names of its keys do not share a hash tag.
+var ErrKeysNotColocated = errors.New("time keeper keys are not co-located")
+
 const (
 	// DefaultRedisExecListName is the default redis key for the list used
 	// to track all executions of tasks managed by the time keeper.
@@ -27,7 +35,8 @@ const (
 //
 // It supports graceful shutdowns via its Stop() function.
 type TimeKeeper struct {
-	client *redis.Client
+	client redis.UniversalClient
+	clock  clock.Clock
 
 	redisExecListName string
 	redisLastExecName string
@@ -48,16 +57,34 @@ func (timeKeeper *TimeKeeper) Stop(ctx context.Context) {
 	}
 }
 
-// NewTimeKeeperWithOptions creates a new TimeKeeper instance.
-func NewTimeKeeperWithOptions(client *redis.Client, options *Options) (*TimeKeeper, error) {
+// NewTimeKeeperWithOptions creates a new TimeKeeper instance. Any redis client
+// is supported - including clusters, sentinels and rings. On a redis cluster or
+// ring, the names of both keys must share a hash tag - e.g.
+// "{timekeeper}.executions.list" and "{timekeeper}.executions.aggregation" - so
+// executions are recorded atomically. Otherwise, ErrKeysNotColocated is returned,
+// unless only one of them is kept.
+func NewTimeKeeperWithOptions(client redis.UniversalClient, options *Options) (*TimeKeeper, error) {
 	if !options.KeepTaskList && !options.KeepLastTask {
 		logrus.Warn(
 			"Time keeper is configured to neither keep the last task nor a task list. This means, this time keeper is a no-op!",
 		)
 	}
 
+	if isDistributed(client) && options.KeepTaskList && options.KeepLastTask &&
+		!colocated(options.RedisExecListName, options.RedisLastExecName) {
+		return nil, fmt.Errorf(
+			"%w: %q and %q do not share a hash tag",
+			ErrKeysNotColocated, options.RedisExecListName, options.RedisLastExecName,
+		)
+	}
+
+	if options.Clock == nil {
+		options.Clock = clock.Real()
+	}
+
 	timeKeeper := &TimeKeeper{
 		client: client,
+		clock:  options.Clock,
 
 		redisExecListName: options.RedisExecListName,
 		redisLastExecName: options.RedisLastExecName,
@@ -80,6 +107,10 @@ func NewTimeKeeperWithOptions(client *redis.Client, options *Options) (*TimeKeep
 
 				crontask.TaskName(options.CleanUpTask.TaskName),
 				crontask.CronExpression("0 * * * * *"),
+				crontask.Clock(options.Clock),
+
+				// Co-locate all keys of the task on a redis cluster, so it can be inspected
+				crontask.TaskKeyspace(crontask.Keyspace{HashTags: isDistributed(options.CleanUpTask.Client)}),
 			)
 			if err != nil {
 				return nil, err
@@ -95,7 +126,7 @@ func NewTimeKeeperWithOptions(client *redis.Client, options *Options) (*TimeKeep
 }
 
 // NewTimeKeeper creates a new TimeKeeper instance.
-func NewTimeKeeper(client *redis.Client, setters ...Option) (*TimeKeeper, error) {
+func NewTimeKeeper(client redis.UniversalClient, setters ...Option) (*TimeKeeper, error) {
 	// Default Options
 	args := &Options{
 		RedisExecListName: DefaultRedisExecListName,
@@ -117,12 +148,18 @@ func NewTimeKeeper(client *redis.Client, setters ...Option) (*TimeKeeper, error)
 
 // WrapCronTask registers a TaskFunc to be recorded via this time keeper.
 // Actual tracking is done via the task, which is provided as part of the
-// wrapped function.
+// wrapped function. Results reported by the task function - via
+// crontask.SetSummary, crontask.AddMetric and crontask.SetOutput - are
+// recorded along with the execution.
+//
+// The last execution and the execution list are written within a transaction.
 func (timeKeeper *TimeKeeper) WrapCronTask(taskFunc crontask.TaskFunc) crontask.TaskFunc {
 	return func(ctx context.Context, task crontask.Task) error {
-		lastExec := time.Now()
+		ctx = crontask.WithResultRecorder(ctx)
+
+		lastExec := timeKeeper.clock.Now()
 		taskErr := taskFunc(ctx, task)
-		lastDuration := time.Since(lastExec)
+		lastDuration := timeKeeper.clock.Now().Sub(lastExec)
 
 		if timeKeeper.keepTaskList || timeKeeper.keepLastTask {
 			if _, err := timeKeeper.client.TxPipelined(ctx, func(pipeliner redis.Pipeliner) error {
@@ -134,6 +171,10 @@ func (timeKeeper *TimeKeeper) WrapCronTask(taskFunc crontask.TaskFunc) crontask.
 					Error:         taskErr,
 				}
 
+				if result, _ := crontask.ResultFromContext(ctx); !result.IsZero() {
+					execution.Result = &result
+				}
+
 				if timeKeeper.keepLastTask {
 					pipeliner.HSet(ctx, timeKeeper.redisLastExecName, task.Name(), execution)
 				}
@@ -157,13 +198,13 @@ func (timeKeeper *TimeKeeper) WrapCronTask(taskFunc crontask.TaskFunc) crontask.
 	}
 }
 
-func (timeKeeper *TimeKeeper) cleanUpOldTaskRuns(ctx context.Context, client *redis.Client, taskListTimeOut time.Duration) error {
-	timeOutPoint := time.Now().Add(-taskListTimeOut)
+func (timeKeeper *TimeKeeper) cleanUpOldTaskRuns(ctx context.Context, client redis.UniversalClient, taskListTimeOut time.Duration) error {
+	timeOutPoint := timeKeeper.clock.Now().Add(-taskListTimeOut)
 
 	for {
 		lastElemList, err := client.LRange(ctx, timeKeeper.redisExecListName, -1, -1).Result()
 		if err != nil {
-			return nil
+			return err
 		}
 
 		// List is empty, nothing to do
@@ -272,6 +313,46 @@ func (timeKeeper *TimeKeeper) GetLastRunOfTask(ctx context.Context, name string)
 	return resultsList[0], nil
 }
 
+// isDistributed returns true, if the given client spreads keys across several
+// redis nodes, so multi key transactions are only atomic within a single slot.
+func isDistributed(client redis.UniversalClient) bool {
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return true
+	default:
+		return false
+	}
+}
+
+// colocated returns true, if the given keys are assigned the same slot of a
+// redis cluster - that is, if the hashed parts of both keys are equal.
+func colocated(key1 string, key2 string) bool {
+	hashed := func(key string) string {
+		if tag := hashTag(key); tag != "" {
+			return tag
+		}
+
+		return key
+	}
+
+	return hashed(key1) == hashed(key2)
+}
+
+// hashTag returns the redis cluster hash tag of the given key - if any.
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start == -1 {
+		return ""
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end < 1 {
+		return ""
+	}
+
+	return key[start+1 : start+1+end]
+}
+
 func unmarshalExecutionResults(results ...string) ([]ExecutionResult, error) {
 	resultList := make([]ExecutionResult, len(results))
 	for i, result := range results {
@@ -1,6 +1,8 @@
 package timekeeper
 
 import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
+
 	"github.com/go-redis/redis/v8"
 	"time"
 )
@@ -15,6 +17,8 @@ type Options struct {
 	KeepLastTask bool
 
 	CleanUpTask *CleanUpOptions
+
+	Clock clock.Clock
 }
 
 // Option represents an option for a time keeper.
@@ -56,9 +60,18 @@ func KeepLastTask(keepTaskList bool) Option {
 	}
 }
 
+// Clock sets the clock, with which executions are timed and old executions are
+// determined. It is passed on to the clean up task.
+// The default is nil, which uses the real clock.
+func Clock(timeKeeperClock clock.Clock) Option {
+	return func(c *Options) {
+		c.Clock = timeKeeperClock
+	}
+}
+
 // CleanUpTask enables the clean up task, which discards old executions.
 // The default is nil.
-func CleanUpTask(client *redis.Client, setters ...CleanUpOption) Option {
+func CleanUpTask(client redis.UniversalClient, setters ...CleanUpOption) Option {
 	return func(c *Options) {
 		if client != nil {
 			c.CleanUpTask = &CleanUpOptions{
@@ -79,7 +92,7 @@ func CleanUpTask(client *redis.Client, setters ...CleanUpOption) Option {
 // CleanUpOptions bundles all available configuration
 // properties for a time keeper clean up task.
 type CleanUpOptions struct {
-	Client       *redis.Client
+	Client       redis.UniversalClient
 	TasksTimeOut time.Duration
 	TaskName     string
 }
@@ -1,6 +1,7 @@
 package timekeeper_test
 
 import (
+	"github.com/kernle32dll/synchronized-cron-task/clock"
 	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
 
 	"github.com/go-redis/redis/v8"
@@ -69,6 +70,22 @@ func Test_TimeKeeperOption_KeepLastTask(t *testing.T) {
 	}
 }
 
+// Tests that the Clock option correctly applies.
+func Test_TimeKeeperOption_Clock(t *testing.T) {
+	// given
+	fakeClock := clock.NewFake(time.Now())
+	option := timekeeper.Clock(fakeClock)
+	options := &timekeeper.Options{Clock: nil}
+
+	// when
+	option(options)
+
+	// then
+	if options.Clock != fakeClock {
+		t.Errorf("clock not correctly applied, got %v", options.Clock)
+	}
+}
+
 // Tests that the TasksTimeOut option correctly applies.
 func Test_TimeKeeperOption_CleanUpTask(t *testing.T) {
 	t.Run("Enable", func(t *testing.T) {
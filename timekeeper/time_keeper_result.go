@@ -1,6 +1,8 @@
 package timekeeper
 
 import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
+
 	"encoding/json"
 	"errors"
 	"time"
@@ -15,6 +17,9 @@ type ExecutionResult struct {
 	LastDuration  time.Duration
 
 	Error error
+
+	// Result is the result reported by the task function - if any.
+	Result *crontask.TaskResult
 }
 
 // ExecutionResultSlice implements sort.Interface based on the Name field.
@@ -24,6 +29,38 @@ func (a ExecutionResultSlice) Len() int           { return len(a) }
 func (a ExecutionResultSlice) Less(i, j int) bool { return a[i].Name < a[j].Name }
 func (a ExecutionResultSlice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
+// RunView is the JSON representation of an ExecutionResult, as exposed by
+// the admin handler and crontaskctl. Unlike the ExecutionResult, it carries
+// the error as a plain string.
+type RunView struct {
+	Name          string               `json:"name"`
+	LastExecution time.Time            `json:"lastExecution"`
+	NextExecution time.Time            `json:"nextExecution"`
+	LastDuration  time.Duration        `json:"lastDuration"`
+	Error         string               `json:"error,omitempty"`
+	Result        *crontask.TaskResult `json:"result,omitempty"`
+}
+
+// NewRunViews returns the views of the given execution results.
+func NewRunViews(results ...ExecutionResult) []RunView {
+	views := make([]RunView, len(results))
+	for i, result := range results {
+		views[i] = RunView{
+			Name:          result.Name,
+			LastExecution: result.LastExecution,
+			NextExecution: result.NextExecution,
+			LastDuration:  result.LastDuration,
+			Result:        result.Result,
+		}
+
+		if result.Error != nil {
+			views[i].Error = result.Error.Error()
+		}
+	}
+
+	return views
+}
+
 // executionResultInternal is an internal wrapper, to allow
 // correct un-/marshalling of errors.
 type executionResultInternal struct {
@@ -34,6 +71,8 @@ type executionResultInternal struct {
 	LastDuration  time.Duration
 
 	Error *string
+
+	Result *crontask.TaskResult `json:",omitempty"`
 }
 
 // MarshalBinary marshalls the ExecutionResult in JSON.
@@ -51,6 +90,7 @@ func (p ExecutionResult) MarshalBinary() ([]byte, error) {
 		NextExecution: p.NextExecution,
 		LastDuration:  p.LastDuration,
 		Error:         errorString,
+		Result:        p.Result,
 	})
 }
 
@@ -65,6 +105,7 @@ func (p *ExecutionResult) UnmarshalBinary(data []byte) error {
 	p.LastExecution = exec.LastExecution
 	p.NextExecution = exec.NextExecution
 	p.LastDuration = exec.LastDuration
+	p.Result = exec.Result
 
 	if exec.Error != nil {
 		p.Error = errors.New(*exec.Error)
@@ -1,8 +1,10 @@
 package timekeeper_test
 
 import (
+	crontask "github.com/kernle32dll/synchronized-cron-task"
 	"github.com/kernle32dll/synchronized-cron-task/timekeeper"
 
+	"encoding/json"
 	"errors"
 	"reflect"
 	"testing"
@@ -64,7 +66,7 @@ func Test_ExecutionResult_UnmarshalBinary(t *testing.T) {
 		Error:         errors.New("some-error"),
 	}
 	if !reflect.DeepEqual(option, expected) {
-		t.Errorf("unexpected marshalling result, got %q, wanted %q", option, expected)
+		t.Errorf("unexpected marshalling result, got %+v, wanted %+v", option, expected)
 	}
 }
 
@@ -84,7 +86,72 @@ func Test_ExecutionResult_UnmarshalBinary_error(t *testing.T) {
 
 	expected := &timekeeper.ExecutionResult{}
 	if !reflect.DeepEqual(option, expected) {
-		t.Errorf("unexpected marshalling result, got %q, wanted %q", option, expected)
+		t.Errorf("unexpected marshalling result, got %+v, wanted %+v", option, expected)
+	}
+}
+
+// Tests that the result of a task function survives binary marshalling.
+func Test_ExecutionResult_MarshalBinary_result(t *testing.T) {
+	// given
+	option := &timekeeper.ExecutionResult{
+		Name: "some-task",
+		Result: &crontask.TaskResult{
+			Summary: "processed 1234 rows",
+			Metrics: map[string]float64{"rows": 1234},
+			Output:  json.RawMessage(`{"table":"users"}`),
+		},
+	}
+
+	// when
+	data, err := option.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error, got %s", err)
+	}
+
+	result := &timekeeper.ExecutionResult{}
+	err = result.UnmarshalBinary(data)
+
+	// then
+	if err != nil {
+		t.Errorf("unexpected error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(result, option) {
+		t.Errorf("unexpected marshalling result, got %+v, wanted %+v", result, option)
+	}
+}
+
+// Tests that execution results are converted into views, carrying errors as strings.
+func Test_NewRunViews(t *testing.T) {
+	// given
+	results := []timekeeper.ExecutionResult{
+		{
+			Name:          "some-task",
+			LastExecution: time.Date(1991, 5, 23, 1, 2, 3, 4, time.UTC),
+			LastDuration:  time.Hour,
+			Error:         errors.New("some-error"),
+			Result:        &crontask.TaskResult{Summary: "processed 1234 rows"},
+		},
+		{Name: "other-task"},
+	}
+
+	// when
+	views := timekeeper.NewRunViews(results...)
+
+	// then
+	expected := []timekeeper.RunView{
+		{
+			Name:          "some-task",
+			LastExecution: time.Date(1991, 5, 23, 1, 2, 3, 4, time.UTC),
+			LastDuration:  time.Hour,
+			Error:         "some-error",
+			Result:        &crontask.TaskResult{Summary: "processed 1234 rows"},
+		},
+		{Name: "other-task"},
+	}
+
+	if !reflect.DeepEqual(views, expected) {
+		t.Errorf("unexpected views, got %+v, wanted %+v", views, expected)
 	}
 }
 
@@ -125,7 +192,7 @@ func Test_ExecutionResult_Slice(t *testing.T) {
 			timekeeper.ExecutionResult{Name: "example3"},
 		}
 		if !reflect.DeepEqual(slice, expected) {
-			t.Fatalf("unexpected execution result slice state after swap, expected %s but got %s", expected, slice)
+			t.Fatalf("unexpected execution result slice state after swap, expected %+v but got %+v", expected, slice)
 		}
 	})
 
@@ -2,6 +2,7 @@ package timekeeper
 
 import (
 	crontask "github.com/kernle32dll/synchronized-cron-task"
+	"github.com/kernle32dll/synchronized-cron-task/clock"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/testcontainers/testcontainers-go"
@@ -10,6 +11,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -41,21 +43,42 @@ func Test_TimeKeeper(t *testing.T) {
 		t.Run(fmt.Sprintf("redis:%s", version), func(t *testing.T) {
 			t.Parallel()
 
-			t.Run("Retrieval", testRetrievalMethods(version))
+			t.Run("Retrieval", testRetrievalMethods(version, getRedisClient))
 
-			t.Run("CleanUp", testCleanup(version))
+			t.Run("CleanUp", testCleanup(version, getRedisClient))
+
+			t.Run("ScheduledCleanUp", testScheduledCleanup(version, getRedisClient))
+
+			t.Run("Cluster", func(t *testing.T) {
+				t.Parallel()
+
+				t.Run("Retrieval", testRetrievalMethods(version, getRedisClusterClient, clusterKeys...))
+
+				t.Run("CleanUp", testCleanup(version, getRedisClusterClient, clusterKeys...))
+
+				t.Run("ScheduledCleanUp", testScheduledCleanup(version, getRedisClusterClient, clusterKeys...))
+			})
 		})
 	}
 }
 
-func testRetrievalMethods(version string) func(t *testing.T) {
+// clientFactory starts a redis container of the given version, and returns a client for it.
+type clientFactory func(t *testing.T, version string) (redis.UniversalClient, func(context.Context) error)
+
+// clusterKeys co-locates the keys of the time keeper on a redis cluster.
+var clusterKeys = []Option{
+	RedisExecListName("{timekeeper}.executions.list"),
+	RedisLastExecName("{timekeeper}.executions.aggregation"),
+}
+
+func testRetrievalMethods(version string, getClient clientFactory, setters ...Option) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Parallel()
 
-		client, closer := getRedisClient(t, version)
+		client, closer := getClient(t, version)
 		defer closeClient(t, client, closer)
 
-		timeKeeper, err := NewTimeKeeper(client, CleanUpTask(nil))
+		timeKeeper, err := NewTimeKeeper(client, append([]Option{CleanUpTask(nil)}, setters...)...)
 		if err != nil {
 			t.Fatalf("unexpected error %q", err)
 		}
@@ -63,6 +86,8 @@ func testRetrievalMethods(version string) func(t *testing.T) {
 		defer timeKeeper.Stop(context.Background())
 
 		testFunc := timeKeeper.WrapCronTask(func(ctx context.Context, task crontask.Task) error {
+			crontask.SetSummary(ctx, fmt.Sprintf("processed %s", task.Name()))
+			crontask.AddMetric(ctx, "rows", 1234)
 			return nil
 		})
 
@@ -200,17 +225,29 @@ func testGetLastRunOfTask(timeKeeper *TimeKeeper, task *TaskMock, minTimeOfSecon
 		if expected := minTimeOfSecondExec; result.LastExecution.Before(expected) {
 			t.Errorf("expected execution time does not match - expected > %q, but got %q", expected, result.LastExecution)
 		}
+
+		if result.Result == nil {
+			t.Fatal("expected task result to be recorded")
+		}
+
+		if expected := "processed " + task.NameVal; result.Result.Summary != expected {
+			t.Errorf("expected task result summary %q, but got %q", expected, result.Result.Summary)
+		}
+
+		if expected := float64(1234); result.Result.Metrics["rows"] != expected {
+			t.Errorf("expected task result metric %v, but got %v", expected, result.Result.Metrics["rows"])
+		}
 	}
 }
 
-func testCleanup(version string) func(t *testing.T) {
+func testCleanup(version string, getClient clientFactory, setters ...Option) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Parallel()
 
-		client, closer := getRedisClient(t, version)
+		client, closer := getClient(t, version)
 		defer closeClient(t, client, closer)
 
-		timeKeeper, err := NewTimeKeeper(client, CleanUpTask(client, CleanUpTasksTimeOut(0)))
+		timeKeeper, err := NewTimeKeeper(client, append([]Option{CleanUpTask(client, CleanUpTasksTimeOut(0))}, setters...)...)
 		if err != nil {
 			t.Fatalf("unexpected error %q", err)
 		}
@@ -250,6 +287,128 @@ func testCleanup(version string) func(t *testing.T) {
 	}
 }
 
+// Tests that the clean up task cleans up upon firings of its cron - not only upon manual executions.
+func testScheduledCleanup(version string, getClient clientFactory, setters ...Option) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := getClient(t, version)
+		defer closeClient(t, client, closer)
+
+		fakeClock := clock.NewFake(time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+		timeKeeper, err := NewTimeKeeper(client, append([]Option{
+			CleanUpTask(client, CleanUpTasksTimeOut(0)),
+			Clock(fakeClock),
+		}, setters...)...)
+		if err != nil {
+			t.Fatalf("unexpected error %q", err)
+		}
+
+		defer timeKeeper.Stop(context.Background())
+
+		testFunc := timeKeeper.WrapCronTask(func(ctx context.Context, task crontask.Task) error {
+			return nil
+		})
+
+		// add a task
+		task := &TaskMock{NameVal: "example1", NextTimeVal: fakeClock.Now().Add(time.Hour * 24)}
+		if err := testFunc(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+
+		// wait for the clean up task to await its first firing, and fire it
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := fakeClock.BlockUntil(ctx, 1); err != nil {
+			t.Fatalf("clean up task did not schedule its firing: %s", err)
+		}
+
+		fakeClock.Advance(time.Minute)
+
+		// the clean up task records its own execution, once it is done
+		for {
+			if _, err := timeKeeper.GetLastRunOfTask(ctx, "timekeeper.cleanup"); err == nil {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("clean up task did not fire")
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		count, err := timeKeeper.CountAllRuns(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error %q", err)
+		}
+
+		if expected := int64(1); count != expected {
+			t.Fatalf("expected count %d, but got %d", expected, count)
+		}
+	}
+}
+
+// Tests that keys, which are not co-located, are refused for redis clusters.
+func Test_NewTimeKeeper_KeysNotColocated(t *testing.T) {
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"does-not-exist:6379"}})
+	closeClient(t, client, nil)
+
+	t.Run("refused", func(t *testing.T) {
+		// when
+		_, err := NewTimeKeeper(client, CleanUpTask(nil))
+
+		// then
+		if !errors.Is(err, ErrKeysNotColocated) {
+			t.Errorf("expected %q, got %q", ErrKeysNotColocated, err)
+		}
+	})
+
+	t.Run("co-located", func(t *testing.T) {
+		// when
+		timeKeeper, err := NewTimeKeeper(client, append([]Option{CleanUpTask(nil)}, clusterKeys...)...)
+
+		// then
+		if err != nil {
+			t.Fatalf("unexpected error %q", err)
+		}
+
+		timeKeeper.Stop(context.Background())
+	})
+
+	t.Run("single-key", func(t *testing.T) {
+		// when
+		timeKeeper, err := NewTimeKeeper(client, CleanUpTask(nil), KeepTaskList(false))
+
+		// then
+		if err != nil {
+			t.Fatalf("unexpected error %q", err)
+		}
+
+		timeKeeper.Stop(context.Background())
+	})
+}
+
+// Tests that hash tags of redis keys are correctly determined.
+func Test_HashTag(t *testing.T) {
+	tests := map[string]string{
+		"timekeeper.executions.list":           "",
+		"{timekeeper}.executions.list":         "timekeeper",
+		"executions.{timekeeper}.list":         "timekeeper",
+		"{}.executions.{timekeeper}":           "",
+		"{timekeeper.executions.list":          "",
+		"{timekeeper}.executions.{other}.list": "timekeeper",
+	}
+
+	for key, expected := range tests {
+		if actual := hashTag(key); actual != expected {
+			t.Errorf("expected hash tag %q for key %q, but got %q", expected, key, actual)
+		}
+	}
+}
+
 func Test_RedisErrors(t *testing.T) {
 	// create client, but immediately close
 	client := redis.NewClient(&redis.Options{
@@ -302,7 +461,7 @@ func testForError(testFunc func(ctx context.Context) error) func(t *testing.T) {
 	}
 }
 
-func closeClient(t *testing.T, client *redis.Client, closer func(context.Context) error) {
+func closeClient(t *testing.T, client redis.UniversalClient, closer func(context.Context) error) {
 	if err := client.Close(); err != nil {
 		t.Logf("unexpected error shutting down redis client: %s", err)
 	}
@@ -314,12 +473,110 @@ func closeClient(t *testing.T, client *redis.Client, closer func(context.Context
 	}
 }
 
-func getRedisClient(t *testing.T, version string) (*redis.Client, func(context.Context) error) {
+func getRedisClient(t *testing.T, version string) (redis.UniversalClient, func(context.Context) error) {
+	redisContainer, address := startRedisContainer(t, version)
+
+	client := redis.NewClient(&redis.Options{
+		Network: "tcp",
+		Addr:    address,
+	})
+
+	return client, redisContainer.Terminate
+}
+
+// clusterNodes is the number of nodes of the redis cluster, started by getRedisClusterClient.
+const clusterNodes = 3
+
+// getRedisClusterClient starts a redis cluster of several nodes, which serve an equal
+// share of the slots. Just like any redis cluster, it rejects commands spanning several
+// slots - and commands for slots served by other nodes.
+func getRedisClusterClient(t *testing.T, version string) (redis.UniversalClient, func(context.Context) error) {
+	ctx := context.Background()
+
+	var (
+		containers []testcontainers.Container
+		nodes      []*redis.Client
+		slots      []redis.ClusterSlot
+	)
+
+	for i := 0; i < clusterNodes; i++ {
+		container, address := startRedisContainer(t, version, "redis-server", "--cluster-enabled", "yes")
+		containers = append(containers, container)
+
+		node := redis.NewClient(&redis.Options{
+			Network: "tcp",
+			Addr:    address,
+		})
+		defer node.Close()
+		nodes = append(nodes, node)
+
+		start, end := i*16384/clusterNodes, (i+1)*16384/clusterNodes-1
+
+		args := []interface{}{"cluster", "addslots"}
+		for slot := start; slot <= end; slot++ {
+			args = append(args, slot)
+		}
+
+		if err := node.Do(ctx, args...).Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		slots = append(slots, redis.ClusterSlot{Start: start, End: end, Nodes: []redis.ClusterNode{{Addr: address}}})
+	}
+
+	// Introduce the first node to all others, via their addresses within the docker network
+	for _, container := range containers[1:] {
+		ip, err := container.ContainerIP(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := nodes[0].ClusterMeet(ctx, ip, "6379").Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for the cluster to accept commands
+	deadline := time.Now().Add(30 * time.Second)
+	for _, node := range nodes {
+		for !strings.Contains(node.ClusterInfo(ctx).Val(), "cluster_state:ok") {
+			if time.Now().After(deadline) {
+				t.Fatal("redis cluster did not become ready")
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	t.Logf("redis cluster of %d nodes started", clusterNodes)
+
+	// The nodes announce their addresses within the docker network, so
+	// the slots are provided manually with the mapped addresses instead
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return slots, nil
+		},
+	})
+
+	return client, func(ctx context.Context) error {
+		var errs []error
+		for _, container := range containers {
+			errs = append(errs, container.Terminate(ctx))
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// startRedisContainer starts a redis container of the given version - optionally
+// with the given command - and returns it along with its mapped address.
+func startRedisContainer(t *testing.T, version string, cmd ...string) (testcontainers.Container, string) {
 	ctx := context.Background()
 
 	req := testcontainers.ContainerRequest{
 		Image:        fmt.Sprintf("redis:%s", version),
 		ExposedPorts: []string{"6379/tcp"},
+		Cmd:          cmd,
 		WaitingFor:   wait.ForLog("Ready to accept connections"),
 	}
 
@@ -346,10 +603,5 @@ func getRedisClient(t *testing.T, version string) (*redis.Client, func(context.C
 
 	t.Logf("redis client started at %q", address)
 
-	client := redis.NewClient(&redis.Options{
-		Network: "tcp",
-		Addr:    address,
-	})
-
-	return client, redisContainer.Terminate
+	return redisContainer, address
 }